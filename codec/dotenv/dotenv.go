@@ -0,0 +1,148 @@
+package dotenv
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gotechbook/pkg/codec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Name is the name registered for the dotenv codec.
+const Name = "dotenv"
+
+func init() {
+	codec.RegisterCodec(code{})
+}
+
+// codec is a Codec implementation with dotenv,
+// the variables are decoded as a flat map of strings.
+type code struct{}
+
+func (code) Marshal(v interface{}) ([]byte, error) {
+	vars, err := toVars(v)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var buf bytes.Buffer
+	for _, name := range names {
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		buf.WriteString(strconv.Quote(vars[name]))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (code) Unmarshal(data []byte, v interface{}) error {
+	vars, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(vars)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (code) Name() string {
+	return Name
+}
+
+func toVars(v interface{}) (map[string]string, error) {
+	if vars, ok := v.(map[string]string); ok {
+		return vars, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	vars := make(map[string]string, len(m))
+	for k, val := range m {
+		if s, ok := val.(string); ok {
+			vars[k] = s
+			continue
+		}
+		b, err := json.Marshal(val)
+		if err != nil {
+			return nil, err
+		}
+		vars[k] = string(b)
+	}
+	return vars, nil
+}
+
+// Parse parses the variables of a .env file. Lines are "KEY=VALUE",
+// optionally prefixed by "export", values may be single or double quoted
+// and span several lines, and "#" starts a comment outside of quotes.
+func Parse(data []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if rest := strings.TrimPrefix(line, "export"); rest != line && len(rest) > 0 && (rest[0] == ' ' || rest[0] == '\t') {
+			line = strings.TrimSpace(rest)
+		}
+		k, v, ok := strings.Cut(line, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid dotenv line %d: %s", i+1, line)
+		}
+		if v == "" || (v[0] != '"' && v[0] != '\'') {
+			if idx := strings.Index(v, " #"); idx != -1 {
+				v = strings.TrimSpace(v[:idx])
+			}
+			vars[k] = v
+			continue
+		}
+		quote, body, start := v[0], v[1:], i
+		end := closingQuote(body, quote)
+		for end == -1 && i+1 < len(lines) {
+			i++
+			body += "\n" + lines[i]
+			end = closingQuote(body, quote)
+		}
+		if end == -1 {
+			return nil, fmt.Errorf("unterminated quoted value at dotenv line %d", start+1)
+		}
+		v = body[:end]
+		if quote == '"' {
+			v = unescape(v)
+		}
+		vars[k] = v
+	}
+	return vars, nil
+}
+
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+var unescaper = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+
+func unescape(s string) string {
+	return unescaper.Replace(s)
+}
@@ -0,0 +1,61 @@
+package dotenv
+
+import "testing"
+
+const _testDotenv = `
+# comment
+export APP_NAME=test
+APP_SERVER__ADDR=":8000" # inline comment
+APP_SERVER__PORTS=[8000, 9000]
+APP_MOTD="hello\nworld"
+APP_RAW='raw\n'
+APP_CERT="-----BEGIN-----
+abc
+-----END-----"
+`
+
+func TestParse(t *testing.T) {
+	vars, err := Parse([]byte(_testDotenv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"APP_NAME":          "test",
+		"APP_SERVER__ADDR":  ":8000",
+		"APP_SERVER__PORTS": "[8000, 9000]",
+		"APP_MOTD":          "hello\nworld",
+		"APP_RAW":           `raw\n`,
+		"APP_CERT":          "-----BEGIN-----\nabc\n-----END-----",
+	}
+	if len(vars) != len(expected) {
+		t.Errorf("expected %d variables, but got: %v", len(expected), vars)
+	}
+	for k, v := range expected {
+		if vars[k] != v {
+			t.Errorf("expected %s=%q, but got: %q", k, v, vars[k])
+		}
+	}
+	if _, err := Parse([]byte(`A="unterminated`)); err == nil {
+		t.Error("expected an error for an unterminated value")
+	}
+	if _, err := Parse([]byte("A=1\nexport\nB=2\n")); err == nil {
+		t.Error("expected an error for a bare export")
+	}
+	if vars, err := Parse([]byte("export=1\n")); err != nil || vars["export"] != "1" {
+		t.Errorf("expected the export key, but got: %v %v", vars, err)
+	}
+
+	data, err := code{}.Marshal(vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]string
+	if err := (code{}).Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range expected {
+		if decoded[k] != v {
+			t.Errorf("expected %s=%q after marshal, but got: %q", k, v, decoded[k])
+		}
+	}
+}
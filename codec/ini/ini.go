@@ -0,0 +1,179 @@
+package ini
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gotechbook/pkg/codec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Name is the name registered for the ini codec.
+const Name = "ini"
+
+func init() {
+	codec.RegisterCodec(code{})
+}
+
+// codec is a Codec implementation with ini, sections such as
+// "[server.grpc]" are decoded into nested maps of strings.
+type code struct{}
+
+func (code) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = writeSection(&buf, "", m); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (code) Unmarshal(data []byte, v interface{}) error {
+	m, err := parse(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (code) Name() string {
+	return Name
+}
+
+// parse parses the ini sections into nested maps. Keys and values are
+// separated by "=" or ":", ";" and "#" start comments and values may be quoted.
+func parse(data []byte) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	section := root
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end == -1 {
+				return nil, fmt.Errorf("invalid ini section at line %d: %s", i+1, line)
+			}
+			name := strings.TrimSpace(line[1:end])
+			if name == "" {
+				return nil, fmt.Errorf("empty ini section at line %d", i+1)
+			}
+			var err error
+			if section, err = subsection(root, name); err != nil {
+				return nil, fmt.Errorf("invalid ini section at line %d: %v", i+1, err)
+			}
+			continue
+		}
+		idx := strings.IndexAny(line, "=:")
+		if idx <= 0 {
+			return nil, fmt.Errorf("invalid ini line %d: %s", i+1, line)
+		}
+		key, value := strings.TrimSpace(line[:idx]), strings.TrimSpace(line[idx+1:])
+		if _, ok := section[key].(map[string]interface{}); ok {
+			return nil, fmt.Errorf("ini key %q at line %d conflicts with a section", key, i+1)
+		}
+		section[key] = unquote(value)
+	}
+	return root, nil
+}
+
+// subsection returns the map of a dotted section name, creating the nested maps.
+func subsection(root map[string]interface{}, name string) (map[string]interface{}, error) {
+	m := root
+	for _, k := range strings.Split(name, ".") {
+		k = strings.TrimSpace(k)
+		switch next := m[k].(type) {
+		case map[string]interface{}:
+			m = next
+		case nil:
+			sub := make(map[string]interface{})
+			m[k] = sub
+			m = sub
+		default:
+			return nil, fmt.Errorf("section %q conflicts with the key %q", name, k)
+		}
+	}
+	return m, nil
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.LastIndexByte(value, value[0]); end > 0 {
+			if value[0] == '"' {
+				if s, err := strconv.Unquote(value[:end+1]); err == nil {
+					return s
+				}
+			}
+			return value[1:end]
+		}
+	}
+	// strip inline comments of unquoted values
+	for _, sep := range []string{" ;", " #"} {
+		if idx := strings.Index(value, sep); idx != -1 {
+			value = strings.TrimSpace(value[:idx])
+		}
+	}
+	return value
+}
+
+// writeSection writes the values of m, followed by the nested maps as sections.
+func writeSection(buf *bytes.Buffer, name string, m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if name != "" {
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		fmt.Fprintf(buf, "[%s]\n", name)
+	}
+	var sections []string
+	for _, k := range keys {
+		var value string
+		switch vt := m[k].(type) {
+		case map[string]interface{}:
+			sections = append(sections, k)
+			continue
+		case string:
+			value = vt
+			if strings.ContainsAny(vt, ";#\"\n") || strings.TrimSpace(vt) != vt {
+				value = strconv.Quote(vt)
+			}
+		case nil:
+		default:
+			b, err := json.Marshal(vt)
+			if err != nil {
+				return err
+			}
+			value = string(b)
+		}
+		fmt.Fprintf(buf, "%s = %s\n", k, value)
+	}
+	for _, k := range sections {
+		sub := k
+		if name != "" {
+			sub = name + "." + k
+		}
+		if err := writeSection(buf, sub, m[k].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package ini
+
+import (
+	"reflect"
+	"testing"
+)
+
+const _testIni = `
+; comment
+# comment
+name = test
+motd = "hello ; world" ; comment
+
+[server]
+addr: :8000 # comment
+
+[server.grpc]
+addr = ':9000'
+`
+
+func TestUnmarshal(t *testing.T) {
+	var m map[string]interface{}
+	if err := (code{}).Unmarshal([]byte(_testIni), &m); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"name": "test",
+		"motd": "hello ; world",
+		"server": map[string]interface{}{
+			"addr": ":8000",
+			"grpc": map[string]interface{}{"addr": ":9000"},
+		},
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected: %v, but got: %v", expected, m)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []string{
+		"[server",
+		"[]",
+		"no separator",
+		"= value",
+		"server = 1\n[server]",
+		"[server]\n[server.addr]\naddr = 1\n[server]\naddr = 2",
+	}
+	for _, test := range tests {
+		var m map[string]interface{}
+		if err := (code{}).Unmarshal([]byte(test), &m); err == nil {
+			t.Errorf("expected an error for %q, but got: %v", test, m)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	in := map[string]interface{}{
+		"name": "test",
+		"motd": " hello ; world\n",
+		"server": map[string]interface{}{
+			"port": 8000,
+			"grpc": map[string]interface{}{"addr": ":9000"},
+		},
+	}
+	data, err := code{}.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err = (code{}).Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"name": "test",
+		"motd": " hello ; world\n",
+		"server": map[string]interface{}{
+			"port": "8000",
+			"grpc": map[string]interface{}{"addr": ":9000"},
+		},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected: %v, but got: %v\n%s", expected, out, data)
+	}
+}
@@ -0,0 +1,233 @@
+package properties
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gotechbook/pkg/codec"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Name is the name registered for the properties codec.
+const Name = "properties"
+
+func init() {
+	codec.RegisterCodec(code{})
+}
+
+// codec is a Codec implementation with Java properties, dotted keys
+// such as "server.grpc.addr" are decoded into nested maps of strings.
+type code struct{}
+
+func (code) Marshal(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err = json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	props := make(map[string]string)
+	if err = flatten("", m, props); err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var buf bytes.Buffer
+	for _, k := range keys {
+		buf.WriteString(escape(k, true))
+		buf.WriteByte('=')
+		buf.WriteString(escape(props[k], false))
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func (code) Unmarshal(data []byte, v interface{}) error {
+	m, err := parse(data)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+func (code) Name() string {
+	return Name
+}
+
+// parse parses the properties into nested maps. Keys and values are separated
+// by "=", ":" or whitespace, "#" and "!" start comment lines and a trailing
+// backslash continues the line.
+func parse(data []byte) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimLeft(lines[i], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		for continued(line) && i+1 < len(lines) {
+			i++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[i], " \t\f")
+		}
+		key, value := split(line)
+		k, err := unescape(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid properties line %d: %v", i+1, err)
+		}
+		v, err := unescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid properties line %d: %v", i+1, err)
+		}
+		if err = set(m, k, v); err != nil {
+			return nil, fmt.Errorf("invalid properties line %d: %v", i+1, err)
+		}
+	}
+	return m, nil
+}
+
+// continued reports whether the line ends with an odd number of backslashes.
+func continued(line string) bool {
+	n := 0
+	for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// split splits the line at the first unescaped separator.
+func split(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '=', ':':
+			return line[:i], strings.TrimLeft(line[i+1:], " \t\f")
+		case ' ', '\t', '\f':
+			rest := strings.TrimLeft(line[i:], " \t\f")
+			if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+				rest = rest[1:]
+			}
+			return line[:i], strings.TrimLeft(rest, " \t\f")
+		}
+	}
+	return line, ""
+}
+
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid unicode escape %q", s[i-1:])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape %q", s[i-1:i+5])
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+func escape(s string, key bool) string {
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case r == '\\' || r == '=' || r == ':' || r == '#' || r == '!':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == ' ' && (key || i == 0):
+			b.WriteString(`\ `)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\f':
+			b.WriteString(`\f`)
+		case r < 0x20:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// set stores value at the dotted key path, creating the nested maps.
+func set(m map[string]interface{}, key, value string) error {
+	keys := strings.Split(key, ".")
+	for _, k := range keys[:len(keys)-1] {
+		switch next := m[k].(type) {
+		case map[string]interface{}:
+			m = next
+		case nil:
+			sub := make(map[string]interface{})
+			m[k] = sub
+			m = sub
+		default:
+			return fmt.Errorf("key %q conflicts with the value of %q", key, k)
+		}
+	}
+	last := keys[len(keys)-1]
+	if _, ok := m[last].(map[string]interface{}); ok {
+		return fmt.Errorf("key %q conflicts with its nested keys", key)
+	}
+	m[last] = value
+	return nil
+}
+
+func flatten(prefix string, m map[string]interface{}, props map[string]string) error {
+	for k, v := range m {
+		switch vt := v.(type) {
+		case map[string]interface{}:
+			if err := flatten(prefix+k+".", vt, props); err != nil {
+				return err
+			}
+		case string:
+			props[prefix+k] = vt
+		case nil:
+			props[prefix+k] = ""
+		default:
+			b, err := json.Marshal(vt)
+			if err != nil {
+				return err
+			}
+			props[prefix+k] = string(b)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package properties
+
+import (
+	"reflect"
+	"testing"
+)
+
+const _testProperties = `
+# comment
+! comment
+name = test
+server.addr: :8000
+server.grpc.addr    \:9000
+server.motd=hello \
+    world
+key\ with\ spaces = a\=b\:c
+escapes = tab\there\nnew line é
+empty
+`
+
+func TestUnmarshal(t *testing.T) {
+	var m map[string]interface{}
+	if err := (code{}).Unmarshal([]byte(_testProperties), &m); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"name": "test",
+		"server": map[string]interface{}{
+			"addr": ":8000",
+			"motd": "hello world",
+			"grpc": map[string]interface{}{"addr": ":9000"},
+		},
+		"key with spaces": "a=b:c",
+		"escapes":         "tab\there\nnew line é",
+		"empty":           "",
+	}
+	if !reflect.DeepEqual(m, expected) {
+		t.Errorf("expected: %v, but got: %v", expected, m)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		line, key, value string
+	}{
+		{"a=b", "a", "b"},
+		{"a:b", "a", "b"},
+		{"a b", "a", "b"},
+		{"a  =  b", "a", "b"},
+		{"a \t: b c", "a", "b c"},
+		{`a\=b=c`, `a\=b`, "c"},
+		{`a\ b c`, `a\ b`, "c"},
+		{"a", "a", ""},
+		{"a=", "a", ""},
+		{"a==b", "a", "=b"},
+	}
+	for _, test := range tests {
+		if k, v := split(test.line); k != test.key || v != test.value {
+			t.Errorf("split(%q) expected: %q %q, but got: %q %q", test.line, test.key, test.value, k, v)
+		}
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []string{
+		`a = \u00`,
+		`a = \uzzzz`,
+		"a = 1\na.b = 2",
+		"a.b = 1\na = 2",
+	}
+	for _, test := range tests {
+		var m map[string]interface{}
+		if err := (code{}).Unmarshal([]byte(test), &m); err == nil {
+			t.Errorf("expected an error for %q, but got: %v", test, m)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	in := map[string]interface{}{
+		"key with spaces": " a=b:c #!\\",
+		"server": map[string]interface{}{
+			"port": 8000,
+			"motd": "hello\n\tworld",
+		},
+	}
+	data, err := code{}.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]interface{}
+	if err = (code{}).Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"key with spaces": " a=b:c #!\\",
+		"server": map[string]interface{}{
+			"port": "8000",
+			"motd": "hello\n\tworld",
+		},
+	}
+	if !reflect.DeepEqual(out, expected) {
+		t.Errorf("expected: %v, but got: %v\n%s", expected, out, data)
+	}
+}
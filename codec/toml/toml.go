@@ -0,0 +1,33 @@
+package toml
+
+import (
+	"bytes"
+	"github.com/BurntSushi/toml"
+	"github.com/gotechbook/pkg/codec"
+)
+
+// Name is the name registered for the toml codec.
+const Name = "toml"
+
+func init() {
+	codec.RegisterCodec(code{})
+}
+
+// codec is a Codec implementation with toml.
+type code struct{}
+
+func (code) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (code) Unmarshal(data []byte, v interface{}) error {
+	return toml.Unmarshal(data, v)
+}
+
+func (code) Name() string {
+	return Name
+}
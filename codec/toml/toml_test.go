@@ -0,0 +1,42 @@
+package toml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	type server struct {
+		Addr  string   `toml:"addr"`
+		Ports []int    `toml:"ports"`
+		Tags  []string `toml:"tags"`
+	}
+	type config struct {
+		Name   string `toml:"name"`
+		Debug  bool   `toml:"debug"`
+		Server server `toml:"server"`
+	}
+	in := config{Name: "test", Debug: true, Server: server{Addr: ":8000", Ports: []int{8000, 9000}, Tags: []string{"a"}}}
+	data, err := code{}.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out config
+	if err = (code{}).Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected: %+v, but got: %+v", in, out)
+	}
+
+	var m map[string]interface{}
+	if err = (code{}).Unmarshal([]byte("[server]\naddr = \":8000\"\n"), &m); err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := m["server"].(map[string]interface{}); !ok || s["addr"] != ":8000" {
+		t.Errorf("unexpected map: %v", m)
+	}
+	if err = (code{}).Unmarshal([]byte("name = "), &m); err == nil {
+		t.Error("expected an error for an invalid document")
+	}
+}
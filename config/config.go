@@ -2,15 +2,22 @@ package config
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gotechbook/pkg/logger"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	// init encoding
+	_ "github.com/gotechbook/pkg/codec/dotenv"
+	_ "github.com/gotechbook/pkg/codec/ini"
 	_ "github.com/gotechbook/pkg/codec/json"
+	_ "github.com/gotechbook/pkg/codec/properties"
+	_ "github.com/gotechbook/pkg/codec/toml"
 	_ "github.com/gotechbook/pkg/codec/yaml"
 )
 
@@ -30,33 +37,64 @@ type Config interface {
 	Scan(v interface{}) error
 	Value(key string) Value
 	Watch(key string, o Observer) error
+	Provenance(key string) (Origin, bool)
+	Snapshot() *Snapshot
+	Rollback() error
 	Close() error
 }
 
 type config struct {
-	opts      options
-	reader    Reader
+	opts   options
+	reader *reader
+	// cached holds the values of the accepted snapshots, which are refreshed on every update
 	cached    sync.Map
-	observers sync.Map
+	cacheLock sync.Mutex
+	lock      sync.Mutex
+	subsLock  sync.RWMutex
+	subs      []*subscription
 	watchers  []Watcher
+	snapshot  atomic.Pointer[Snapshot]
+	version   uint64
+	history   []revision
+	pending   []func()
+}
+
+// subscription is an observer registered for a key or a key prefix.
+type subscription struct {
+	key      string
+	prefix   bool
+	observer Observer
 }
 
 // New a config with options.
 func New(opts ...Option) Config {
 	o := options{
-		decoder:  defaultDecoder,
-		resolver: defaultResolver,
+		decoder: defaultDecoder,
+		secrets: defaultSecrets(),
+		arrays:  make(map[string]ArrayMerge),
+		history: 10,
 	}
 	for _, opt := range opts {
 		opt(&o)
 	}
+	o.layers = activeLayers(o)
+	if o.resolver == nil {
+		o.resolver = newResolver(o.secrets, o.decrypter)
+	}
 	return &config{
 		opts:   o,
 		reader: newReader(o),
 	}
 }
 
-func (c *config) watch(w Watcher) {
+// the delays of retrying a watcher failing repeatedly.
+const (
+	minWatchBackoff = time.Second
+	maxWatchBackoff = time.Minute
+)
+
+func (c *config) watch(source int, w Watcher) {
+	var backoff time.Duration
 	for {
 		kvs, err := w.Next()
 		if err != nil {
@@ -64,87 +102,278 @@ func (c *config) watch(w Watcher) {
 				logger.Infof("watcher's ctx cancel : %v", err)
 				return
 			}
-			time.Sleep(time.Second)
-			logger.Errorf("failed to watch next config: %v", err)
+			backoff = nextBackoff(backoff)
+			logger.Errorf("failed to watch next config: %v, retry in %v", err, backoff)
+			time.Sleep(backoff)
 			continue
 		}
-		if err := c.reader.Merge(kvs...); err != nil {
-			logger.Errorf("failed to merge next config: %v", err)
+		backoff = 0
+		c.lock.Lock()
+		prev := c.reader.state()
+		if err = c.reader.merge(source, false, kvs...); err != nil {
+			c.unlock()
+			c.reject(fmt.Errorf("failed to merge next config: %w", err))
 			continue
 		}
-		if err := c.reader.Resolve(); err != nil {
-			logger.Errorf("failed to resolve next config: %v", err)
+		if err = c.reader.Resolve(); err != nil {
+			c.reader.restore(prev)
+			c.unlock()
+			c.reject(fmt.Errorf("failed to resolve next config: %w", err))
 			continue
 		}
-		c.cached.Range(func(key, value interface{}) bool {
-			k := key.(string)
-			v := value.(Value)
-			if n, ok := c.reader.Value(k); ok && reflect.TypeOf(n.Load()) == reflect.TypeOf(v.Load()) && !reflect.DeepEqual(n.Load(), v.Load()) {
-				v.Store(n.Load())
-				if o, ok := c.observers.Load(k); ok {
-					o.(Observer)(k, v)
-				}
+		if err = validate(c.opts, c.reader.snapshot()); err != nil {
+			c.reader.restore(prev)
+			c.unlock()
+			c.reject(fmt.Errorf("rejected next config: %w", err))
+			continue
+		}
+		if err = c.commit(prev); err != nil {
+			c.unlock()
+			c.reject(fmt.Errorf("rejected next config: %w", err))
+			continue
+		}
+		c.unlock()
+	}
+}
+
+// commit applies the values of the reader as a new snapshot unless it is vetoed,
+// in which case the reader is restored to prev.
+func (c *config) commit(prev readerState) error {
+	cur := c.snapshot.Load()
+	state := c.reader.state()
+	next := &Snapshot{version: c.version + 1, time: time.Now(), values: state.values, origins: state.origins, maskKeys: c.opts.maskKeys}
+	if cur != nil && c.opts.veto != nil {
+		if err := c.opts.veto(cur, next); err != nil {
+			c.reader.restore(prev)
+			return fmt.Errorf("%w: %v", ErrVetoed, err)
+		}
+	}
+	c.version = next.version
+	if cur != nil && c.opts.history > 0 {
+		c.history = append(c.history, revision{state: prev, snapshot: cur})
+		if len(c.history) > c.opts.history {
+			c.history = c.history[len(c.history)-c.opts.history:]
+		}
+	}
+	c.snapshot.Store(next)
+	if cur != nil {
+		logChanges(cur, next, c.opts.maskKeys)
+	}
+	c.notify(prev.values, next.values)
+	return nil
+}
+
+// nextBackoff doubles the backoff between minWatchBackoff and maxWatchBackoff.
+func nextBackoff(backoff time.Duration) time.Duration {
+	if backoff *= 2; backoff < minWatchBackoff {
+		return minWatchBackoff
+	}
+	if backoff > maxWatchBackoff {
+		return maxWatchBackoff
+	}
+	return backoff
+}
+
+// reject reports a watched update which was not applied.
+func (c *config) reject(err error) {
+	logger.Errorf("%v", err)
+	if c.opts.onError != nil {
+		c.opts.onError(err)
+	}
+}
+
+// unlock releases the lock, then calls the observers queued by notify,
+// so that observers can use the config and do not block the updates.
+func (c *config) unlock() {
+	pending := c.pending
+	c.pending = nil
+	c.lock.Unlock()
+	for _, call := range pending {
+		call()
+	}
+}
+
+// notify refreshes the cached values and queues the calls of the observers
+// of the keys which changed between prev and next, it must be called with the lock held
+// after the snapshot of next is stored.
+func (c *config) notify(prev, next map[string]interface{}) {
+	c.cacheLock.Lock()
+	c.cached.Range(func(key, value interface{}) bool {
+		k := key.(string)
+		v := value.(Value)
+		if n, ok := readValue(next, k); !ok {
+			v.Store(nil)
+			c.cached.Delete(k)
+		} else if !reflect.DeepEqual(n.Load(), v.Load()) {
+			v.Store(n.Load())
+		}
+		return true
+	})
+	c.cacheLock.Unlock()
+
+	c.subsLock.RLock()
+	subs := c.subs
+	c.subsLock.RUnlock()
+	var prevLeaves, nextLeaves map[string]interface{}
+	for _, s := range subs {
+		if !s.prefix {
+			if v, ok := changedValue(prev, next, s.key); ok {
+				c.queue(s.observer, s.key, v)
 			}
-			return true
-		})
+			continue
+		}
+		if prevLeaves == nil {
+			prevLeaves, nextLeaves = flatten(prev), flatten(next)
+		}
+		for _, k := range changedKeys(prevLeaves, nextLeaves, s.key) {
+			if v, ok := changedValue(prev, next, k); ok {
+				c.queue(s.observer, k, v)
+			}
+		}
 	}
 }
 
+func (c *config) queue(o Observer, key string, v Value) {
+	c.pending = append(c.pending, func() { o(key, v) })
+}
+
+// Load loads the sources, then watches them once the loaded config is applied.
+// The watchers of a previous Load are replaced.
 func (c *config) Load() error {
-	for _, src := range c.opts.sources {
+	c.lock.Lock()
+	defer c.unlock()
+	prev := c.reader.state()
+	watchers := make([]Watcher, 0, len(c.opts.layers))
+	fail := func(err error) error {
+		c.reader.restore(prev)
+		stopWatchers(watchers)
+		return err
+	}
+	for i, l := range c.opts.layers {
+		src := l.source
 		kvs, err := src.Load()
 		if err != nil {
-			return err
+			return fail(err)
 		}
 		for _, v := range kvs {
 			logger.Debugf("config loaded: %s format: %s", v.Key, v.Format)
 		}
-		if err = c.reader.Merge(kvs...); err != nil {
+		if err = c.reader.merge(i, true, kvs...); err != nil {
 			logger.Errorf("failed to merge config source: %v", err)
-			return err
+			return fail(err)
 		}
 		w, err := src.Watch()
 		if err != nil {
 			logger.Errorf("failed to watch config source: %v", err)
-			return err
+			return fail(err)
 		}
-		c.watchers = append(c.watchers, w)
-		go c.watch(w)
+		watchers = append(watchers, w)
 	}
 	if err := c.reader.Resolve(); err != nil {
 		logger.Errorf("failed to resolve config source: %v", err)
-		return err
+		return fail(err)
+	}
+	if err := validate(c.opts, c.reader.snapshot()); err != nil {
+		logger.Errorf("failed to validate config source: %v", err)
+		return fail(err)
+	}
+	if err := c.commit(prev); err != nil {
+		logger.Errorf("failed to apply config source: %v", err)
+		return fail(err)
+	}
+	stopWatchers(c.watchers)
+	c.watchers = watchers
+	// the watcher of a layer merges into the layer of the same index
+	for i, w := range watchers {
+		go c.watch(i, w)
 	}
 	return nil
 }
 
+// stopWatchers stops the watchers, logging the failures.
+func stopWatchers(watchers []Watcher) {
+	for _, w := range watchers {
+		if err := w.Stop(); err != nil {
+			logger.Errorf("failed to stop config watcher: %v", err)
+		}
+	}
+}
+
+// Value returns the value of key in the last accepted snapshot, so that the updates
+// being validated or vetoed are never seen. The value is refreshed on every update.
 func (c *config) Value(key string) Value {
 	if v, ok := c.cached.Load(key); ok {
 		return v.(Value)
 	}
-	if v, ok := c.reader.Value(key); ok {
-		c.cached.Store(key, v)
-		return v
+	// the snapshot is read under the cache lock, so that an update
+	// either refreshes the cached value or is read by it
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+	if v, ok := readValue(c.Snapshot().values, key); ok {
+		actual, _ := c.cached.LoadOrStore(key, v)
+		return actual.(Value)
 	}
 	return &errValue{err: ErrNotFound}
 }
 
 func (c *config) Scan(v interface{}) error {
-	data, err := c.reader.Source()
-	if err != nil {
-		return err
+	return c.Snapshot().Scan(v)
+}
+
+// Snapshot returns the current snapshot, it is swapped atomically on every update.
+// Values read from a snapshot are consistent across keys.
+func (c *config) Snapshot() *Snapshot {
+	if s := c.snapshot.Load(); s != nil {
+		return s
 	}
-	return json.Unmarshal(data, v)
+	return &Snapshot{values: make(map[string]interface{})}
 }
 
+// Rollback restores the values of the previous snapshot as a new version, observers are notified of the changes.
+func (c *config) Rollback() error {
+	c.lock.Lock()
+	defer c.unlock()
+	if len(c.history) == 0 {
+		return ErrNoHistory
+	}
+	r := c.history[len(c.history)-1]
+	c.history = c.history[:len(c.history)-1]
+	prev := c.reader.state()
+	c.reader.restore(r.state)
+	// the restored values are a new version, so that versions only increase
+	c.version++
+	next := &Snapshot{version: c.version, time: time.Now(), values: r.snapshot.values, origins: r.snapshot.origins, maskKeys: c.opts.maskKeys}
+	logChanges(c.Snapshot(), next, c.opts.maskKeys)
+	c.snapshot.Store(next)
+	c.notify(prev.values, next.values)
+	return nil
+}
+
+// Watch registers an observer of key, a key can have any number of observers.
+// A key ending with "*" observes every key with the given prefix, e.g. "server.*".
+// Observers are called when the value changes, including changes of its type,
+// and receive a Value returning ErrNotFound when the key is deleted.
+// Observers run after the update is applied and may use the config, e.g. Load or Rollback.
 func (c *config) Watch(key string, o Observer) error {
-	if v := c.Value(key); v.Load() == nil {
+	s := &subscription{key: key, observer: o}
+	if strings.HasSuffix(key, "*") {
+		s.key, s.prefix = strings.TrimSuffix(key, "*"), true
+	} else if v := c.Value(key); v.Load() == nil {
 		return ErrNotFound
 	}
-	c.observers.Store(key, o)
+	c.subsLock.Lock()
+	c.subs = append(c.subs, s)
+	c.subsLock.Unlock()
 	return nil
 }
 
+// Provenance returns the source supplying the effective value of the leaf key
+// in the last accepted snapshot.
+func (c *config) Provenance(key string) (Origin, bool) {
+	o, ok := c.Snapshot().origins[key]
+	return o, ok
+}
+
 func (c *config) Close() error {
 	for _, w := range c.watchers {
 		if err := w.Stop(); err != nil {
@@ -153,3 +382,55 @@ func (c *config) Close() error {
 	}
 	return nil
 }
+
+// changedValue reports whether the value of key differs between prev and next,
+// a deleted key is reported with a Value returning ErrNotFound.
+func changedValue(prev, next map[string]interface{}, key string) (Value, bool) {
+	p, pok := readValue(prev, key)
+	n, nok := readValue(next, key)
+	switch {
+	case pok && !nok:
+		return &errValue{err: ErrNotFound}, true
+	case nok && (!pok || !reflect.DeepEqual(p.Load(), n.Load())):
+		return n, true
+	}
+	return nil, false
+}
+
+// changedKeys returns the sorted leaf keys with the given prefix
+// which were added, deleted or modified.
+func changedKeys(prev, next map[string]interface{}, prefix string) []string {
+	var keys []string
+	for k, v := range next {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if p, ok := prev[k]; !ok || !reflect.DeepEqual(p, v) {
+			keys = append(keys, k)
+		}
+	}
+	for k := range prev {
+		if _, ok := next[k]; !ok && strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// flatten returns the leaf values of src keyed by their dotted path.
+func flatten(src map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{})
+	var walk func(string, map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if sub, ok := v.(map[string]interface{}); ok && len(sub) > 0 {
+				walk(prefix+k+".", sub)
+				continue
+			}
+			dst[prefix+k] = v
+		}
+	}
+	walk("", src)
+	return dst
+}
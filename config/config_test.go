@@ -0,0 +1,558 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testSource struct {
+	kvs []*KeyValue
+	ch  chan []*KeyValue
+}
+
+func newTestSource(data string) *testSource {
+	return &testSource{
+		kvs: []*KeyValue{{Key: "test.json", Value: []byte(data), Format: "json"}},
+		ch:  make(chan []*KeyValue),
+	}
+}
+
+func (s *testSource) Load() ([]*KeyValue, error) { return s.kvs, nil }
+func (s *testSource) Watch() (Watcher, error)    { return s, nil }
+func (s *testSource) Stop() error                { return nil }
+
+func (s *testSource) Next() ([]*KeyValue, error) {
+	kvs, ok := <-s.ch
+	if !ok {
+		return nil, context.Canceled
+	}
+	return kvs, nil
+}
+
+func (s *testSource) update(data string) {
+	s.ch <- []*KeyValue{{Key: "test.json", Value: []byte(data), Format: "json"}}
+}
+
+type testEvents struct {
+	lock   sync.Mutex
+	events map[string][]interface{}
+	ch     chan struct{}
+}
+
+func (e *testEvents) observe(key string, v Value) {
+	e.lock.Lock()
+	e.events[key] = append(e.events[key], v.Load())
+	e.lock.Unlock()
+	e.ch <- struct{}{}
+}
+
+func (e *testEvents) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-e.ch:
+		case <-time.After(time.Second):
+			t.Fatalf("expected %d events, but got: %d", n, i)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	src := newTestSource(`{"server":{"addr":":8000","timeout":"1s"},"name":"test"}`)
+	c := New(WithSource(src))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer close(src.ch)
+
+	events := &testEvents{events: make(map[string][]interface{}), ch: make(chan struct{}, 16)}
+	if err := c.Watch("name", events.observe); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Watch("name", events.observe); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Watch("server.*", events.observe); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Watch("not_found", events.observe); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, but got: %v", err)
+	}
+	type server struct {
+		Addr    string `json:"addr"`
+		Timeout string `json:"timeout"`
+	}
+	w, err := NewWatched[server](c, "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := w.Load(); s.Addr != ":8000" {
+		t.Errorf("expected :8000, but got: %v", s.Addr)
+	}
+
+	// name changes type, server.timeout is deleted and server.port is added
+	src.update(`{"server":{"addr":":9000","port":9000},"name":1}`)
+	events.wait(t, 5)
+	events.lock.Lock()
+	if v := events.events["name"]; len(v) != 2 || v[0] != float64(1) {
+		t.Errorf("unexpected name events: %v", v)
+	}
+	if v := events.events["server.addr"]; len(v) != 1 || v[0] != ":9000" {
+		t.Errorf("unexpected server.addr events: %v", v)
+	}
+	if v := events.events["server.port"]; len(v) != 1 || v[0] != float64(9000) {
+		t.Errorf("unexpected server.port events: %v", v)
+	}
+	if v := events.events["server.timeout"]; len(v) != 1 || v[0] != nil {
+		t.Errorf("unexpected server.timeout events: %v", v)
+	}
+	events.lock.Unlock()
+	if s := w.Load(); s.Addr != ":9000" || s.Timeout != "" {
+		t.Errorf("unexpected watched value: %+v", s)
+	}
+	if v, _ := c.Value("name").Int(); v != 1 {
+		t.Errorf("expected 1, but got: %v", v)
+	}
+	if _, err := c.Value("server.timeout").String(); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, but got: %v", err)
+	}
+}
+
+func TestReaderMergeDeleted(t *testing.T) {
+	r := newReader(options{decoder: defaultDecoder, resolver: defaultResolver})
+	if err := r.merge(0, true, &KeyValue{Key: "a.json", Value: []byte(`{"a":1,"b":1}`), Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.merge(1, true, &KeyValue{Key: "server.addr", Value: []byte(":8000")}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.merge(0, false, &KeyValue{Key: "b.json", Value: []byte(`{"b":2}`), Format: "json"}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := r.Value("b"); v == nil || v.Load() != float64(2) {
+		t.Errorf("expected b to be 2, but got: %v", v)
+	}
+	if err := r.merge(0, false, &KeyValue{Key: "a.json", Deleted: true}, &KeyValue{Key: "server.addr", Deleted: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := r.Value("a"); ok {
+		t.Error("expected a to be deleted")
+	}
+	if v, ok := r.Value("server.addr"); !ok || v.Load() != ":8000" {
+		t.Errorf("expected server.addr of another source to be kept, but got: %v", v)
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := NewJSONSchema([]byte(`{
+		"type": "object",
+		"properties": {
+			"server": {
+				"type": "object",
+				"properties": {"port": {"type": "integer", "maximum": 65535}},
+				"required": ["port"]
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type server struct {
+		Port int `json:"port"`
+	}
+	schemas := map[string]Schema{
+		"json":   schema,
+		"struct": NewStructSchema(&struct{ Server server }{}),
+	}
+	for name, schema := range schemas {
+		t.Run(name, func(t *testing.T) {
+			typo := `{"server":{"port":8000,"prot":9000}}`
+			if err := New(WithSource(newTestSource(typo)), WithSchema(schema)).Load(); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			err := New(WithSource(newTestSource(typo)), WithSchema(schema), WithStrict(true)).Load()
+			if !errors.Is(err, ErrInvalid) {
+				t.Errorf("expected ErrInvalid, but got: %v", err)
+			}
+
+			rejected := make(chan error, 1)
+			s := newTestSource(`{"server":{"port":8000}}`)
+			c := New(WithSource(s), WithSchema(schema), WithErrorHandler(func(err error) { rejected <- err }))
+			if err = c.Load(); err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			s.update(`{"server":{"port":"http"}}`)
+			select {
+			case err = <-rejected:
+				if !errors.Is(err, ErrInvalid) {
+					t.Errorf("expected ErrInvalid, but got: %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected the update to be rejected")
+			}
+			if port, _ := c.Value("server.port").Int(); port != 8000 {
+				t.Errorf("expected the last valid port 8000, but got: %v", port)
+			}
+		})
+	}
+}
+
+func TestSchemaCoerce(t *testing.T) {
+	schema, err := NewJSONSchema([]byte(`{
+		"type": "object",
+		"definitions": {
+			"base": {"type": "object", "properties": {"port": {"type": "integer"}}}
+		},
+		"properties": {
+			"server": {
+				"allOf": [
+					{"$ref": "#/definitions/base"},
+					{"type": "object", "properties": {"debug": {"type": "boolean"}, "tags": {"type": "array", "items": {"type": "number"}}}}
+				]
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	type server struct {
+		Port  int  `json:"port"`
+		Debug bool `json:"debug"`
+	}
+	schemas := map[string]Schema{
+		"json":   schema,
+		"struct": NewStructSchema(&struct{ Server *server }{}),
+	}
+	for name, schema := range schemas {
+		t.Run(name, func(t *testing.T) {
+			// the values of the env and flag sources are strings
+			values := map[string]interface{}{"server": map[string]interface{}{"port": "9000", "debug": "true"}}
+			if err := schema.Validate(values, true); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if values["server"].(map[string]interface{})["port"] != "9000" {
+				t.Error("expected the values not to be modified")
+			}
+			values = map[string]interface{}{"server": map[string]interface{}{"port": "http"}}
+			if err := schema.Validate(values, false); err == nil {
+				t.Error("expected an error for an invalid port")
+			}
+			values = map[string]interface{}{"server": map[string]interface{}{"port": 1, "prot": 2}}
+			if err := schema.Validate(values, true); err == nil && name == "struct" {
+				t.Error("expected an error for an unknown field in strict mode")
+			}
+		})
+	}
+	timeouts := NewStructSchema(&struct {
+		Timeout time.Duration  `json:"timeout"`
+		Retry   *time.Duration `json:"retry"`
+	}{})
+	if err := timeouts.Validate(map[string]interface{}{"timeout": "5s", "retry": "100"}, true); err != nil {
+		t.Errorf("expected the durations to be parsed, but got: %v", err)
+	}
+	if err := timeouts.Validate(map[string]interface{}{"timeout": "5 seconds"}, true); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+	values := map[string]interface{}{"server": map[string]interface{}{"tags": []interface{}{"1.5"}}}
+	if err := schema.Validate(values, true); err != nil {
+		t.Errorf("expected the items to be converted, but got: %v", err)
+	}
+}
+
+func TestLayers(t *testing.T) {
+	c := New(
+		WithLayer(newTestSource(`{"debug":false,"servers":[{"name":"a","port":1}]}`), WithName("prod"), WithProfile("prod"), WithPriority(10)),
+		WithLayer(newTestSource(`{"debug":true,"tags":["x"],"servers":[{"name":"a","port":2}]}`), WithName("dev"), WithProfile("dev"), WithPriority(10)),
+		WithLayer(newTestSource(`{"debug":true,"level":"info","tags":["base"],"servers":[{"name":"a","port":0},{"name":"b","port":3}]}`), WithName("base"), WithProfile(BaseProfile)),
+		WithProfiles("prod"),
+		WithArrayMerge(ArrayMergeByKey("name"), "servers"),
+		WithArrayMerge(ArrayAppend),
+	)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if debug, _ := c.Value("debug").Bool(); debug {
+		t.Error("expected debug to be overridden by the prod profile")
+	}
+	var v struct {
+		Servers []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"servers"`
+	}
+	if err := c.Scan(&v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Servers) != 2 || v.Servers[0].Port != 1 || v.Servers[1].Name != "b" {
+		t.Errorf("unexpected servers: %+v", v.Servers)
+	}
+	if tags, _ := c.Value("tags").Strings(); len(tags) != 1 || tags[0] != "base" {
+		t.Errorf("expected the tags of the dev profile to be skipped, but got: %v", tags)
+	}
+	if o, ok := c.Provenance("debug"); !ok || o.Source != "prod" || o.Priority != 10 {
+		t.Errorf("unexpected provenance of debug: %+v", o)
+	}
+	if o, ok := c.Provenance("level"); !ok || o.Source != "base" || o.Key != "test.json" {
+		t.Errorf("unexpected provenance of level: %+v", o)
+	}
+}
+
+func TestActiveProfiles(t *testing.T) {
+	tests := []struct {
+		args []string
+		env  string
+		want string
+	}{
+		{nil, "dev", "dev"},
+		{[]string{"--profile=prod,eu"}, "dev", "prod,eu"},
+		{[]string{"-profile", "prod"}, "", "prod"},
+		{[]string{"--", "--profile=prod"}, "", ""},
+	}
+	for _, test := range tests {
+		if got := strings.Join(activeProfiles(test.args, test.env), ","); got != test.want {
+			t.Errorf("activeProfiles(%v, %q) expected: %q, but got: %q", test.args, test.env, test.want, got)
+		}
+	}
+}
+
+// testSchema rejects the negative ports, reading the config port when it does.
+type testSchema struct {
+	c    Config
+	seen chan int64
+}
+
+func (s *testSchema) Validate(values map[string]interface{}, strict bool) error {
+	if v, ok := readValue(values, "port"); ok {
+		if p, _ := v.Int(); p >= 0 {
+			return nil
+		}
+	}
+	port, _ := s.c.Value("port").Int()
+	s.seen <- port
+	return errors.New("port must not be negative")
+}
+
+func TestValueOfRejectedUpdate(t *testing.T) {
+	s := newTestSource(`{"port":8000}`)
+	schema := &testSchema{seen: make(chan int64, 1)}
+	rejected := make(chan error, 1)
+	c := New(WithSource(s), WithSchema(schema), WithErrorHandler(func(err error) { rejected <- err }))
+	schema.c = c
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	s.update(`{"port":-1}`)
+	select {
+	case err := <-rejected:
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, but got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the update to be rejected")
+	}
+	if port := <-schema.seen; port != 8000 {
+		t.Errorf("expected the validation to read the accepted port 8000, but got: %d", port)
+	}
+	if port, _ := c.Value("port").Int(); port != 8000 {
+		t.Errorf("expected the accepted port 8000 after the rejection, but got: %d", port)
+	}
+}
+
+// testWatchSource returns a new watcher on every Watch, which is done when stopped.
+type testWatchSource struct {
+	*testSource
+	watchers []*testWatcher
+}
+
+type testWatcher struct {
+	done chan struct{}
+}
+
+func (s *testWatchSource) Watch() (Watcher, error) {
+	w := &testWatcher{done: make(chan struct{})}
+	s.watchers = append(s.watchers, w)
+	return w, nil
+}
+
+func (w *testWatcher) Next() ([]*KeyValue, error) {
+	<-w.done
+	return nil, context.Canceled
+}
+
+func (w *testWatcher) Stop() error {
+	close(w.done)
+	return nil
+}
+
+func (w *testWatcher) stopped() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestLoadWatchers(t *testing.T) {
+	s := &testWatchSource{testSource: newTestSource(`{"port":-1}`)}
+	schema := &testSchema{seen: make(chan int64, 1)}
+	c := New(WithSource(s), WithSchema(schema))
+	schema.c = c
+	if err := c.Load(); !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, but got: %v", err)
+	}
+	if len(s.watchers) != 1 || !s.watchers[0].stopped() {
+		t.Fatal("expected the watcher of the failed load to be stopped")
+	}
+	if len(c.(*config).watchers) != 0 {
+		t.Errorf("expected no watchers after the failed load, but got: %d", len(c.(*config).watchers))
+	}
+
+	s.kvs[0].Value = []byte(`{"port":8000}`)
+	for i := 0; i < 2; i++ {
+		if err := c.Load(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.watchers) != 3 || !s.watchers[1].stopped() || s.watchers[2].stopped() {
+		t.Error("expected the watcher of the previous load to be replaced")
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	s := newTestSource(`{"a":1,"b":1}`)
+	rejected := make(chan error, 1)
+	var c Config
+	c = New(
+		WithSource(s),
+		WithVeto(func(prev, next *Snapshot) error {
+			// the values of the update are not seen before it is applied
+			want, _ := prev.Value("b").Int()
+			if b, _ := c.Value("b").Int(); b != want {
+				return fmt.Errorf("expected b %d, but got: %d", want, b)
+			}
+			if b, _ := next.Value("b").Int(); b < 0 {
+				return errors.New("b must not be negative")
+			}
+			return nil
+		}),
+		WithErrorHandler(func(err error) { rejected <- err }),
+	)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	first := c.Snapshot()
+
+	events := &testEvents{events: make(map[string][]interface{}), ch: make(chan struct{}, 16)}
+	if err := c.Watch("a", events.observe); err != nil {
+		t.Fatal(err)
+	}
+	s.update(`{"a":2,"b":2}`)
+	events.wait(t, 1)
+	next := c.Snapshot()
+	if next.Version() != first.Version()+1 {
+		t.Errorf("expected version %d, but got: %d", first.Version()+1, next.Version())
+	}
+	if a, _ := first.Value("a").Int(); a != 1 {
+		t.Errorf("expected the first snapshot to be immutable, but got a: %d", a)
+	}
+
+	s.update(`{"a":3,"b":-1}`)
+	select {
+	case err := <-rejected:
+		if !errors.Is(err, ErrVetoed) {
+			t.Errorf("expected ErrVetoed, but got: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the update to be vetoed")
+	}
+	if c.Snapshot() != next {
+		t.Error("expected the vetoed update not to be applied")
+	}
+	if b, _ := c.Value("b").Int(); b != 2 {
+		t.Errorf("expected the accepted b 2 after the veto, but got: %d", b)
+	}
+	if b, _ := c.Snapshot().Value("b").Int(); b != 2 {
+		t.Errorf("expected the snapshot b 2 after the veto, but got: %v", b)
+	}
+
+	rec := httptest.NewRecorder()
+	NewAdminHandler(c).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/config/rollback", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the rollback without authorization to be forbidden, but got: %d", rec.Code)
+	}
+	auth := WithAdminAuth(func(r *http.Request) error {
+		if r.Header.Get("Authorization") != "token" {
+			return errors.New("invalid token")
+		}
+		return nil
+	})
+	rec = httptest.NewRecorder()
+	NewAdminHandler(c, auth).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/config/rollback", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the rollback without token to be unauthorized, but got: %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/config/rollback", nil)
+	req.Header.Set("Authorization", "token")
+	NewAdminHandler(c, auth).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d %s", rec.Code, rec.Body)
+	}
+	events.wait(t, 1)
+	if v := c.Snapshot().Version(); v <= next.Version() {
+		t.Errorf("expected the rollback to increase the version %d, but got: %d", next.Version(), v)
+	}
+	if a, _ := c.Value("a").Int(); a != 1 {
+		t.Errorf("expected a to be rolled back to 1, but got: %d", a)
+	}
+	if err := c.Rollback(); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("expected ErrNoHistory, but got: %v", err)
+	}
+}
+
+func TestObserverUsesConfig(t *testing.T) {
+	s := newTestSource(`{"a":1}`)
+	c := New(WithSource(s))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	done := make(chan int64, 1)
+	if err := c.Watch("a", func(key string, v Value) {
+		if n, _ := v.Int(); n != 2 {
+			done <- n
+			return
+		}
+		// the config is unlocked while the observers run
+		if err := c.Rollback(); err != nil {
+			t.Error(err)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	s.update(`{"a":2}`)
+	select {
+	case n := <-done:
+		if n != 1 {
+			t.Errorf("expected the rolled back value 1, but got: %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the observer deadlocked")
+	}
+}
@@ -1,59 +1,204 @@
 package env
 
 import (
+	"encoding/json"
+	"github.com/gotechbook/pkg/codec"
+	"github.com/gotechbook/pkg/codec/dotenv"
 	"github.com/gotechbook/pkg/config"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 )
 
+var _ config.Source = (*env)(nil)
+
+type Option func(*env)
+
 type env struct {
-	prefixes []string
+	prefixes  []string
+	separator string
+	lowercase bool
+	format    string
+	files     []string
+
+	// the variables of the last Load, which the watcher compares changes against
+	lock sync.Mutex
+	vars map[string]string
+}
+
+// WithPrefix only loads the variables with one of the prefixes, which are trimmed from the keys.
+func WithPrefix(prefixes ...string) Option {
+	return func(e *env) {
+		e.prefixes = prefixes
+	}
+}
+
+// WithSeparator maps variable names into dotted keys by
+// replacing sep with ".", e.g. "SERVER__ADDR" into "SERVER.ADDR" with "__".
+func WithSeparator(sep string) Option {
+	return func(e *env) {
+		e.separator = sep
+	}
+}
+
+// WithLowerCase folds the keys into lower case, e.g. "SERVER.ADDR" into "server.addr".
+func WithLowerCase() Option {
+	return func(e *env) {
+		e.lowercase = true
+	}
+}
+
+// WithValueFormat decodes every value with the format codec, e.g. "yaml" coerces
+// "8000" into an int and "[a, b]" into a list. Values which fail to decode are kept as strings.
+func WithValueFormat(format string) Option {
+	return func(e *env) {
+		e.format = format
+	}
 }
 
+// WithFile loads the variables of .env files, which are overridden by the
+// process environment. The files are watched and changes are reloaded.
+func WithFile(paths ...string) Option {
+	return func(e *env) {
+		e.files = paths
+	}
+}
+
+// NewSource new an env source loading the variables with one of the prefixes.
 func NewSource(prefixes ...string) config.Source {
-	return &env{prefixes: prefixes}
+	return New(WithPrefix(prefixes...))
+}
+
+// New new an env source with options.
+func New(opts ...Option) config.Source {
+	e := &env{}
+	for _, o := range opts {
+		o(e)
+	}
+	return e
 }
 
 func (e *env) Load() (kv []*config.KeyValue, err error) {
-	return e.load(os.Environ()), nil
+	vars, err := e.load()
+	if err != nil {
+		return nil, err
+	}
+	e.lock.Lock()
+	e.vars = vars
+	e.lock.Unlock()
+	return e.keyValues(vars, nil), nil
 }
 
-func (e *env) load(envs []string) []*config.KeyValue {
-	var kv []*config.KeyValue
-	for _, env := range envs {
-		var k, v string
-		subs := strings.SplitN(env, "=", 2) //nolint:gomnd
-		k = subs[0]
-		if len(subs) > 1 {
-			v = subs[1]
+// load returns the matching variables of the env files and the process environment.
+func (e *env) load() (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, path := range e.files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
 		}
-
-		if len(e.prefixes) > 0 {
-			p, ok := matchPrefix(e.prefixes, k)
-			if !ok || len(p) == len(k) {
-				continue
-			}
-			// trim prefix
-			k = strings.TrimPrefix(k, p)
-			k = strings.TrimPrefix(k, "_")
+		fvs, err := dotenv.Parse(data)
+		if err != nil {
+			return nil, err
 		}
-
-		if len(k) != 0 {
-			kv = append(kv, &config.KeyValue{
-				Key:   k,
-				Value: []byte(v),
-			})
+		for k, v := range fvs {
+			vars[k] = v
 		}
 	}
+	for _, env := range os.Environ() {
+		k, v, _ := strings.Cut(env, "=")
+		vars[k] = v
+	}
+	for k := range vars {
+		if _, ok := e.key(k); !ok {
+			delete(vars, k)
+		}
+	}
+	return vars, nil
+}
+
+// keyValues returns the key values of vars sorted by name,
+// and the names of deleted as deleted key values.
+func (e *env) keyValues(vars map[string]string, deleted []string) []*config.KeyValue {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Strings(deleted)
+	kvs := make([]*config.KeyValue, 0, len(names)+len(deleted))
+	for _, name := range names {
+		kvs = append(kvs, e.keyValue(name, vars[name]))
+	}
+	for _, name := range deleted {
+		key, _ := e.key(name)
+		kvs = append(kvs, &config.KeyValue{Key: key, Deleted: true})
+	}
+	return kvs
+}
+
+func (e *env) keyValue(name, value string) *config.KeyValue {
+	key, _ := e.key(name)
+	kv := &config.KeyValue{
+		Key:   key,
+		Value: []byte(value),
+	}
+	if e.format == "" {
+		return kv
+	}
+	c := codec.GetCodec(e.format)
+	if c == nil {
+		return kv
+	}
+	var v interface{}
+	if err := c.Unmarshal([]byte(value), &v); err != nil {
+		return kv
+	}
+	// nest the typed value under its key, as the decoder only expands
+	// the keys of key values without format.
+	keys := strings.Split(key, ".")
+	for i := len(keys) - 1; i >= 0; i-- {
+		v = map[string]interface{}{keys[i]: v}
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return kv
+	}
+	kv.Value, kv.Format = data, "json"
 	return kv
 }
 
+// key maps a variable name into its config key,
+// reporting false if the name doesn't match the prefixes.
+func (e *env) key(name string) (string, bool) {
+	k := name
+	if len(e.prefixes) > 0 {
+		p, ok := matchPrefix(e.prefixes, k)
+		if !ok || len(p) == len(k) {
+			return "", false
+		}
+		// trim prefix
+		k = strings.TrimPrefix(k, p)
+		k = strings.TrimPrefix(k, "_")
+	}
+	if e.separator != "" {
+		k = strings.ReplaceAll(k, e.separator, ".")
+	}
+	if e.lowercase {
+		k = strings.ToLower(k)
+	}
+	return k, len(k) != 0
+}
+
 func (e *env) Watch() (config.Watcher, error) {
-	w, err := NewWatcher()
-	if err != nil {
-		return nil, err
+	if len(e.files) == 0 {
+		return NewWatcher()
 	}
-	return w, nil
+	e.lock.Lock()
+	vars := e.vars
+	e.lock.Unlock()
+	return newFileWatcher(e, vars)
 }
 
 func matchPrefix(prefixes []string, s string) (string, bool) {
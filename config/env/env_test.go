@@ -0,0 +1,79 @@
+package env
+
+import (
+	"github.com/gotechbook/pkg/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const _testDotenv = `
+# comment
+export APP_NAME=test
+APP_SERVER__ADDR=":8000" # inline comment
+APP_SERVER__PORTS=[8000, 9000]
+APP_SERVER__TLS=true
+APP_MOTD="hello\nworld"
+APP_RAW='raw\n'
+APP_CERT="-----BEGIN-----
+abc
+-----END-----"
+`
+
+func TestSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(_testDotenv), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("APP_SERVER__ADDR", ":9000")
+
+	c := config.New(config.WithSource(New(
+		WithPrefix("APP_"),
+		WithSeparator("__"),
+		WithLowerCase(),
+		WithValueFormat("yaml"),
+		WithFile(path),
+	)))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var conf struct {
+		Name   string `json:"name"`
+		Server struct {
+			Addr  string `json:"addr"`
+			Ports []int  `json:"ports"`
+			TLS   bool   `json:"tls"`
+		} `json:"server"`
+	}
+	if err := c.Scan(&conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "test" || conf.Server.Addr != ":9000" || len(conf.Server.Ports) != 2 || !conf.Server.TLS {
+		t.Errorf("unexpected config: %+v", conf)
+	}
+
+	ch := make(chan config.Value, 1)
+	if err := c.Watch("name", func(_ string, v config.Value) { ch <- v }); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("APP_SERVER__TLS=false\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case v := <-ch:
+		if _, err := v.String(); err != config.ErrNotFound {
+			t.Errorf("expected name to be deleted, but got: %v", v.Load())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected name to be deleted")
+	}
+	if tls, _ := c.Value("server.tls").Bool(); tls {
+		t.Error("expected server.tls to be false")
+	}
+	if addr, _ := c.Value("server.addr").String(); addr != ":9000" {
+		t.Errorf("expected :9000, but got: %v", addr)
+	}
+}
@@ -2,10 +2,15 @@ package env
 
 import (
 	"context"
+	"github.com/fsnotify/fsnotify"
 	"github.com/gotechbook/pkg/config"
+	"path/filepath"
 )
 
-var _ config.Watcher = (*watcher)(nil)
+var (
+	_ config.Watcher = (*watcher)(nil)
+	_ config.Watcher = (*fileWatcher)(nil)
+)
 
 type watcher struct {
 	ctx    context.Context
@@ -27,3 +32,79 @@ func (w *watcher) Stop() error {
 	w.cancel()
 	return nil
 }
+
+// fileWatcher reloads the variables when one of the env files changes.
+type fileWatcher struct {
+	e     *env
+	fw    *fsnotify.Watcher
+	files map[string]struct{}
+	vars  map[string]string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newFileWatcher(e *env, vars map[string]string) (config.Watcher, error) {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]struct{}, len(e.files))
+	for _, path := range e.files {
+		// watch the directory, so the files replaced by editors are still watched
+		if err := fw.Add(filepath.Dir(path)); err != nil {
+			_ = fw.Close()
+			return nil, err
+		}
+		files[filepath.Clean(path)] = struct{}{}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &fileWatcher{e: e, fw: fw, files: files, vars: vars, ctx: ctx, cancel: cancel}, nil
+}
+
+func (w *fileWatcher) Next() ([]*config.KeyValue, error) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return nil, w.ctx.Err()
+		case event := <-w.fw.Events:
+			if _, ok := w.files[filepath.Clean(event.Name)]; !ok || event.Op == fsnotify.Chmod {
+				continue
+			}
+			vars, err := w.e.load()
+			if err != nil {
+				return nil, err
+			}
+			kvs := w.changes(vars)
+			w.vars = vars
+			if len(kvs) > 0 {
+				return kvs, nil
+			}
+		case err := <-w.fw.Errors:
+			return nil, err
+		}
+	}
+}
+
+// changes returns the key values of the variables which
+// differ from the last loaded ones, including deleted ones.
+func (w *fileWatcher) changes(vars map[string]string) []*config.KeyValue {
+	changed := make(map[string]string)
+	for k, v := range vars {
+		if p, ok := w.vars[k]; !ok || p != v {
+			changed[k] = v
+		}
+	}
+	var deleted []string
+	for k := range w.vars {
+		if _, ok := vars[k]; !ok {
+			deleted = append(deleted, k)
+		}
+	}
+	return w.e.keyValues(changed, deleted)
+}
+
+func (w *fileWatcher) Stop() error {
+	w.cancel()
+	return w.fw.Close()
+}
@@ -0,0 +1,127 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"github.com/gotechbook/pkg/config"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+	"path"
+	"strings"
+	"sync"
+)
+
+var _ config.Source = (*source)(nil)
+
+// etcdClient is the part of the etcd client used by the source.
+type etcdClient interface {
+	clientv3.KV
+	clientv3.Watcher
+}
+
+type source struct {
+	client etcdClient
+	opts   *options
+
+	// the revision and keys of the last Load, from where the watcher starts
+	lock     sync.Mutex
+	revision int64
+	keys     map[string]struct{}
+}
+
+// NewSource new an etcd source.
+func NewSource(client *clientv3.Client, opts ...Option) (config.Source, error) {
+	op := &options{
+		ctx: context.Background(),
+	}
+	for _, o := range opts {
+		o(op)
+	}
+	if op.path == "" {
+		return nil, errors.New("etcd config path is empty")
+	}
+	return &source{client: client, opts: op}, nil
+}
+
+func (s *source) Load() ([]*config.KeyValue, error) {
+	kvs, revision, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	s.revision, s.keys = revision, keySet(kvs)
+	s.lock.Unlock()
+	return kvs, nil
+}
+
+func (s *source) Watch() (config.Watcher, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	keys := make(map[string]struct{}, len(s.keys))
+	for k := range s.keys {
+		keys[k] = struct{}{}
+	}
+	return newWatcher(s, s.revision, keys), nil
+}
+
+// load returns the key values of the path and the revision they were read at.
+func (s *source) load() ([]*config.KeyValue, int64, error) {
+	var opts []clientv3.OpOption
+	if s.opts.prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	resp, err := s.client.Get(s.opts.ctx, s.key(), opts...)
+	if err != nil {
+		return nil, 0, err
+	}
+	kvs := make([]*config.KeyValue, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs = append(kvs, s.keyValue(kv))
+	}
+	return kvs, resp.Header.Revision, nil
+}
+
+// key returns the key to get and watch, a prefix ends with "/"
+// so that the path "/app" does not match "/application".
+func (s *source) key() string {
+	if s.opts.prefix && !strings.HasSuffix(s.opts.path, "/") {
+		return s.opts.path + "/"
+	}
+	return s.opts.path
+}
+
+func (s *source) keyValue(kv *mvccpb.KeyValue) *config.KeyValue {
+	key := string(kv.Key)
+	f := s.format(key)
+	if f == "" {
+		// "/app/server/addr" under the path "/app" is keyed "server.addr"
+		key = strings.Trim(strings.TrimPrefix(key, s.opts.path), "/")
+		if key == "" {
+			key = path.Base(string(kv.Key))
+		}
+		key = strings.ReplaceAll(key, "/", ".")
+	}
+	return &config.KeyValue{
+		Key:    key,
+		Value:  kv.Value,
+		Format: f,
+	}
+}
+
+func (s *source) format(key string) string {
+	if s.opts.format != "" {
+		return s.opts.format
+	}
+	if p := strings.Split(path.Base(key), "."); len(p) > 1 {
+		return p[len(p)-1]
+	}
+	return ""
+}
+
+func keySet(kvs []*config.KeyValue) map[string]struct{} {
+	keys := make(map[string]struct{}, len(kvs))
+	for _, kv := range kvs {
+		keys[kv.Key] = struct{}{}
+	}
+	return keys
+}
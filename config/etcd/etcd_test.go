@@ -0,0 +1,119 @@
+package etcd
+
+import (
+	"context"
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"go.etcd.io/etcd/client/v3"
+	"testing"
+)
+
+// testClient is an in-memory etcd client of a single prefix.
+type testClient struct {
+	clientv3.KV
+	clientv3.Watcher
+
+	kvs      []*mvccpb.KeyValue
+	revision int64
+	ch       chan clientv3.WatchResponse
+	watched  []string
+}
+
+func (c *testClient) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	op := clientv3.OpGet(key, opts...)
+	resp := &clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: c.revision}}
+	for _, kv := range c.kvs {
+		k := string(kv.Key)
+		if k == key || (len(op.RangeBytes()) > 0 && k >= key && k < string(op.RangeBytes())) {
+			resp.Kvs = append(resp.Kvs, kv)
+		}
+	}
+	return resp, nil
+}
+
+func (c *testClient) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	c.watched = append(c.watched, key)
+	return c.ch
+}
+
+func TestKeyValue(t *testing.T) {
+	s := &source{opts: &options{path: "/app", prefix: true}}
+	tests := []struct {
+		key    string
+		want   string
+		format string
+	}{
+		{"/app/server/addr", "server.addr", ""},
+		{"/app/config.yaml", "/app/config.yaml", "yaml"},
+		{"/app/db/config.json", "/app/db/config.json", "json"},
+	}
+	for _, test := range tests {
+		kv := s.keyValue(&mvccpb.KeyValue{Key: []byte(test.key), Value: []byte("v")})
+		if kv.Key != test.want || kv.Format != test.format {
+			t.Errorf("keyValue(%s) expected: %s %q, but got: %s %q", test.key, test.want, test.format, kv.Key, kv.Format)
+		}
+	}
+	s = &source{opts: &options{path: "/app/config", format: "yaml"}}
+	if kv := s.keyValue(&mvccpb.KeyValue{Key: []byte("/app/config")}); kv.Key != "/app/config" || kv.Format != "yaml" {
+		t.Errorf("unexpected key value: %+v", kv)
+	}
+}
+
+func TestSource(t *testing.T) {
+	c := &testClient{
+		kvs: []*mvccpb.KeyValue{
+			{Key: []byte("/app/config.yaml"), Value: []byte("a: 1")},
+			{Key: []byte("/app/server/addr"), Value: []byte(":8000")},
+			{Key: []byte("/application/name"), Value: []byte("other")},
+		},
+		revision: 10,
+		ch:       make(chan clientv3.WatchResponse, 1),
+	}
+	s := &source{client: c, opts: &options{ctx: context.Background(), path: "/app", prefix: true}}
+	kvs, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 2 || kvs[0].Key != "/app/config.yaml" || kvs[1].Key != "server.addr" {
+		t.Fatalf("expected the keys under /app/ only, but got: %+v", kvs)
+	}
+
+	w, err := s.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	if len(c.watched) != 1 || c.watched[0] != "/app/" {
+		t.Fatalf("expected to watch /app/, but got: %v", c.watched)
+	}
+
+	c.ch <- clientv3.WatchResponse{
+		Header: pb.ResponseHeader{Revision: 11},
+		Events: []*clientv3.Event{
+			{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("/app/server/port"), Value: []byte("9000")}},
+			{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("/app/config.yaml")}},
+		},
+	}
+	kvs, err = w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 2 || kvs[0].Key != "server.port" || kvs[0].Deleted || kvs[1].Key != "/app/config.yaml" || !kvs[1].Deleted {
+		t.Fatalf("unexpected key values: %+v", kvs)
+	}
+
+	// after a compaction the path is reloaded, and the keys gone since are reported as deleted
+	c.kvs = []*mvccpb.KeyValue{{Key: []byte("/app/server/port"), Value: []byte("9001")}}
+	c.revision = 20
+	c.ch <- clientv3.WatchResponse{CompactRevision: 15}
+	kvs, err = w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 2 || kvs[0].Key != "server.port" || string(kvs[0].Value) != "9001" || kvs[1].Key != "server.addr" || !kvs[1].Deleted {
+		t.Fatalf("unexpected reloaded key values: %+v", kvs)
+	}
+	if ww := w.(*watcher); ww.revision != 20 || len(c.watched) != 2 {
+		t.Errorf("expected to watch again from the reloaded revision, but got: %d %v", ww.revision, c.watched)
+	}
+}
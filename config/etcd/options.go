@@ -0,0 +1,41 @@
+package etcd
+
+import "context"
+
+type Option func(o *options)
+
+type options struct {
+	ctx    context.Context
+	path   string
+	prefix bool
+	format string
+}
+
+// WithContext with the context of the etcd requests.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithPath with the key to load, or the key prefix when WithPrefix is used.
+func WithPath(p string) Option {
+	return func(o *options) {
+		o.path = p
+	}
+}
+
+// WithPrefix loads every key under the path, i.e. the keys starting with the path and "/".
+func WithPrefix(prefix bool) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithFormat with the format of the values, by default
+// it is inferred from the key extension, e.g. "/app/config.yaml".
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
@@ -0,0 +1,119 @@
+package etcd
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/config"
+	"go.etcd.io/etcd/client/v3"
+	"time"
+)
+
+var _ config.Watcher = (*watcher)(nil)
+
+type watcher struct {
+	source   *source
+	ch       clientv3.WatchChan
+	revision int64
+	keys     map[string]struct{}
+
+	ctx         context.Context
+	cancel      context.CancelFunc
+	watchCancel context.CancelFunc
+}
+
+func newWatcher(s *source, revision int64, keys map[string]struct{}) *watcher {
+	w := &watcher{
+		source:   s,
+		revision: revision,
+		keys:     keys,
+	}
+	w.ctx, w.cancel = context.WithCancel(s.opts.ctx)
+	w.watch()
+	return w
+}
+
+// watch starts watching after the last seen revision,
+// so no change is lost when the watch channel is recreated.
+func (w *watcher) watch() {
+	if w.watchCancel != nil {
+		w.watchCancel()
+	}
+	var (
+		ctx  context.Context
+		opts []clientv3.OpOption
+	)
+	ctx, w.watchCancel = context.WithCancel(w.ctx)
+	if w.source.opts.prefix {
+		opts = append(opts, clientv3.WithPrefix())
+	}
+	if w.revision > 0 {
+		opts = append(opts, clientv3.WithRev(w.revision+1))
+	}
+	w.ch = w.source.client.Watch(ctx, w.source.key(), opts...)
+}
+
+func (w *watcher) Next() ([]*config.KeyValue, error) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return nil, w.ctx.Err()
+		case resp, ok := <-w.ch:
+			if !ok {
+				if w.ctx.Err() != nil {
+					return nil, w.ctx.Err()
+				}
+				time.Sleep(time.Second)
+				w.watch()
+				continue
+			}
+			if resp.CompactRevision != 0 {
+				// the revision after the last seen one has been compacted,
+				// so the changes in between are recovered with a full reload.
+				return w.reload()
+			}
+			if err := resp.Err(); err != nil {
+				w.watch()
+				return nil, err
+			}
+			if len(resp.Events) == 0 {
+				continue
+			}
+			w.revision = resp.Header.Revision
+			kvs := make([]*config.KeyValue, 0, len(resp.Events))
+			for _, ev := range resp.Events {
+				kv := w.source.keyValue(ev.Kv)
+				if ev.Type == clientv3.EventTypeDelete {
+					kv.Value, kv.Deleted = nil, true
+					delete(w.keys, kv.Key)
+				} else {
+					w.keys[kv.Key] = struct{}{}
+				}
+				kvs = append(kvs, kv)
+			}
+			return kvs, nil
+		}
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return nil
+}
+
+// reload reads the whole path again and reports the keys
+// which disappeared since the last response as deleted.
+func (w *watcher) reload() ([]*config.KeyValue, error) {
+	kvs, revision, err := w.source.load()
+	if err != nil {
+		return nil, err
+	}
+	keys := keySet(kvs)
+	for k := range w.keys {
+		if _, ok := keys[k]; !ok {
+			kvs = append(kvs, &config.KeyValue{Key: k, Deleted: true})
+		}
+	}
+	w.keys = keys
+	w.revision = revision
+	w.watch()
+	return kvs, nil
+}
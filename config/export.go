@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"github.com/gotechbook/pkg/codec"
+	"github.com/gotechbook/pkg/logger"
+	"strings"
+)
+
+// ChangeType is the type of a changed key.
+type ChangeType string
+
+const (
+	ChangeAdded   ChangeType = "added"
+	ChangeRemoved ChangeType = "removed"
+	ChangeChanged ChangeType = "changed"
+)
+
+// Change is a leaf key changed between two snapshots.
+type Change struct {
+	Key  string
+	Type ChangeType
+	Old  interface{}
+	New  interface{}
+}
+
+// Export encodes the merged and resolved values of the snapshot with the codec
+// of format, e.g. "json" or "yaml". Secrets and the values of the mask keys are masked,
+// a mask key matches a full key path such as "db.password" or a key name such as "password".
+func (s *Snapshot) Export(format string, maskKeys ...string) ([]byte, error) {
+	c := codec.GetCodec(format)
+	if c == nil {
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+	return c.Marshal(maskValues(s.values, "", newMasks(append(append([]string{}, s.maskKeys...), maskKeys...))))
+}
+
+// Diff returns the leaf keys added, removed or changed from prev to next, sorted by key.
+func Diff(prev, next *Snapshot) []Change {
+	var p, n map[string]interface{}
+	if prev != nil {
+		p = flatten(prev.values)
+	}
+	if next != nil {
+		n = flatten(next.values)
+	}
+	var changes []Change
+	for _, k := range changedKeys(p, n, "") {
+		pv, pok := p[k]
+		nv, nok := n[k]
+		switch {
+		case !pok:
+			changes = append(changes, Change{Key: k, Type: ChangeAdded, New: nv})
+		case !nok:
+			changes = append(changes, Change{Key: k, Type: ChangeRemoved, Old: pv})
+		default:
+			changes = append(changes, Change{Key: k, Type: ChangeChanged, Old: pv, New: nv})
+		}
+	}
+	return changes
+}
+
+type masks map[string]struct{}
+
+func newMasks(keys []string) masks {
+	m := make(masks, len(keys))
+	for _, k := range keys {
+		m[k] = struct{}{}
+	}
+	return m
+}
+
+func (m masks) match(path string) bool {
+	if _, ok := m[path]; ok {
+		return true
+	}
+	_, ok := m[path[strings.LastIndex(path, ".")+1:]]
+	return ok
+}
+
+// maskValues returns a copy of src at the key path with its secrets and mask keys masked.
+func maskValues(src interface{}, path string, m masks) interface{} {
+	if _, ok := src.(Secret); ok || (src != nil && path != "" && m.match(path)) {
+		return secretMask
+	}
+	switch vt := src.(type) {
+	case map[string]interface{}:
+		dst := make(map[string]interface{}, len(vt))
+		for k, v := range vt {
+			p := k
+			if path != "" {
+				p = path + "." + k
+			}
+			dst[k] = maskValues(v, p, m)
+		}
+		return dst
+	case []interface{}:
+		dst := make([]interface{}, len(vt))
+		for i, v := range vt {
+			dst[i] = maskValues(v, path, m)
+		}
+		return dst
+	default:
+		return src
+	}
+}
+
+// logChanges logs the changes from prev to next, masking the values of the mask keys.
+func logChanges(prev, next *Snapshot, maskKeys []string) {
+	m := newMasks(maskKeys)
+	for _, ch := range Diff(prev, next) {
+		logger.Infow(
+			"msg", "config changed",
+			"version", next.version,
+			"key", ch.Key,
+			"type", ch.Type,
+			"old", maskValues(ch.Old, ch.Key, m),
+			"new", maskValues(ch.New, ch.Key, m),
+		)
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	t.Setenv("TEST_EXPORT_TOKEN", "t0ken")
+	c := New(WithSource(newTestSource(`{"db":{"password":"p4ss","addr":"db:3306"},"token":"${secret:env:TEST_EXPORT_TOKEN}"}`)))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	data, err := c.Snapshot().Export("json", "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]interface{}
+	if err = json.Unmarshal(data, &v); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"db":    map[string]interface{}{"password": secretMask, "addr": "db:3306"},
+		"token": secretMask,
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("expected: %v, but got: %v", want, v)
+	}
+
+	data, err = c.Snapshot().Export("yaml", "db.addr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := string(data); strings.Contains(s, "t0ken") || strings.Contains(s, "db:3306") || !strings.Contains(s, "p4ss") {
+		t.Errorf("unexpected yaml: %s", s)
+	}
+	if _, err = c.Snapshot().Export("xml"); err == nil {
+		t.Error("expected an error of the unsupported format")
+	}
+}
+
+func TestAdminHandlerMasks(t *testing.T) {
+	c := New(WithSource(newTestSource(`{"db":{"password":"p4ss","addr":"db:3306"}}`)), WithMaskKeys("db.password"))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	rec := httptest.NewRecorder()
+	NewAdminHandler(c).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/config", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unexpected status: %d %s", rec.Code, rec.Body)
+	}
+	if s := rec.Body.String(); strings.Contains(s, "p4ss") || !strings.Contains(s, "db:3306") {
+		t.Errorf("unexpected snapshot: %s", s)
+	}
+	if p, _ := c.Value("db.password").String(); p != "p4ss" {
+		t.Errorf("expected the values not to be masked, but got: %s", p)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	prev := &Snapshot{values: map[string]interface{}{
+		"a": 1,
+		"b": map[string]interface{}{"c": "x", "d": true},
+	}}
+	next := &Snapshot{values: map[string]interface{}{
+		"b": map[string]interface{}{"c": "y", "d": true},
+		"e": []interface{}{1},
+	}}
+	want := []Change{
+		{Key: "a", Type: ChangeRemoved, Old: 1},
+		{Key: "b.c", Type: ChangeChanged, Old: "x", New: "y"},
+		{Key: "e", Type: ChangeAdded, New: []interface{}{1}},
+	}
+	if got := Diff(prev, next); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected: %v, but got: %v", want, got)
+	}
+	if got := Diff(next, next); len(got) != 0 {
+		t.Errorf("expected no changes, but got: %v", got)
+	}
+}
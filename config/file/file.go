@@ -1,22 +1,75 @@
 package file
 
 import (
+	"fmt"
+	"github.com/gotechbook/pkg/codec"
 	"github.com/gotechbook/pkg/config"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 var _ config.Source = (*file)(nil)
 
+// includeKey is the key of the include directive of the YAML and JSON files,
+// its value is a path or a list of paths relative to the file, globs are allowed.
+const includeKey = "include"
+
+// Option is file source option.
+type Option func(*file)
+
 type file struct {
-	path string
+	path      string
+	recursive bool
+	globs     []string
+	debounce  time.Duration
+}
+
+// WithRecursive loads the files of the subdirectories, keyed by their relative path.
+func WithRecursive() Option {
+	return func(f *file) {
+		f.recursive = true
+	}
+}
+
+// WithGlob only loads the files of the directory matching one of the patterns,
+// which are matched against the relative path and the base name, e.g. "*.yaml".
+func WithGlob(patterns ...string) Option {
+	return func(f *file) {
+		f.globs = append(f.globs, patterns...)
+	}
+}
+
+// WithDebounce with the quiet period the watcher waits for after an event
+// before reloading, so the several writes of a save are loaded once, 100ms by default.
+func WithDebounce(d time.Duration) Option {
+	return func(f *file) {
+		f.debounce = d
+	}
 }
 
 // NewSource new a file source.
-func NewSource(path string) config.Source {
-	return &file{path: path}
+// The files of a directory are loaded in lexical order of their relative path,
+// the files included by a file are loaded before it.
+func NewSource(path string, opts ...Option) config.Source {
+	f := &file{path: path, debounce: 100 * time.Millisecond}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// loader loads the files of a source once, recording the watched directories.
+type loader struct {
+	f       *file
+	root    string
+	kvs     []*config.KeyValue
+	visited map[string]struct{}
+	dirs    map[string]struct{}
 }
 
 func (f *file) loadFile(path string) (*config.KeyValue, error) {
@@ -40,38 +93,176 @@ func (f *file) loadFile(path string) (*config.KeyValue, error) {
 	}, nil
 }
 
-func (f *file) loadDir(path string) (kvs []*config.KeyValue, err error) {
-	files, err := os.ReadDir(path)
+// load loads the source, returning its key values and the directories to watch.
+func (f *file) load() ([]*config.KeyValue, map[string]struct{}, error) {
+	fi, err := os.Stat(f.path)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	for _, file := range files {
-		// ignore hidden files
-		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
-			continue
-		}
-		kv, err := f.loadFile(filepath.Join(path, file.Name()))
+	l := &loader{
+		f:       f,
+		root:    filepath.Dir(f.path),
+		visited: make(map[string]struct{}),
+		dirs:    make(map[string]struct{}),
+	}
+	if fi.IsDir() {
+		l.root = f.path
+		err = l.loadDir(f.path)
+	} else {
+		l.dirs[l.root] = struct{}{}
+		err = l.load(f.path)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return l.kvs, l.dirs, nil
+}
+
+func (l *loader) loadDir(path string) error {
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
 		if err != nil {
-			return nil, err
+			return err
 		}
-		kvs = append(kvs, kv)
+		// ignore the ..data and ..<timestamp> directories of ConfigMaps, whose files are linked
+		// from the directory, the other hidden files such as .env are loaded
+		if p != path && strings.HasPrefix(d.Name(), "..") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			if p != path && !l.f.recursive {
+				return filepath.SkipDir
+			}
+			l.dirs[p] = struct{}{}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			if fi, err := os.Stat(p); err != nil || fi.IsDir() {
+				return nil
+			}
+		}
+		if !l.f.match(l.rel(p)) {
+			return nil
+		}
+		return l.load(p)
+	})
+}
+
+func (f *file) match(rel string) bool {
+	if len(f.globs) == 0 {
+		return true
 	}
-	return
+	for _, pattern := range f.globs {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(rel)); ok {
+			return true
+		}
+	}
+	return false
 }
 
-func (f *file) Load() (kvs []*config.KeyValue, err error) {
-	fi, err := os.Stat(f.path)
+// rel returns the key of the file, its path relative to the root.
+func (l *loader) rel(path string) string {
+	rel, err := filepath.Rel(l.root, path)
 	if err != nil {
-		return nil, err
+		return filepath.ToSlash(path)
 	}
-	if fi.IsDir() {
-		return f.loadDir(f.path)
+	return filepath.ToSlash(rel)
+}
+
+// load loads the files included by the file at path and then the file.
+func (l *loader) load(path string) error {
+	path = filepath.Clean(path)
+	if _, ok := l.visited[path]; ok {
+		return nil
+	}
+	l.visited[path] = struct{}{}
+	kv, err := l.f.loadFile(path)
+	if err != nil {
+		return err
+	}
+	kv.Key = l.rel(path)
+	includes, err := stripIncludes(kv)
+	if err != nil {
+		return fmt.Errorf("failed to read includes of %s: %w", path, err)
+	}
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		matches, err := filepath.Glob(include)
+		if err != nil {
+			return err
+		}
+		if len(matches) == 0 && !isGlob(include) {
+			return fmt.Errorf("included file of %s not found: %s", path, include)
+		}
+		sort.Strings(matches)
+		for _, m := range matches {
+			l.dirs[filepath.Dir(m)] = struct{}{}
+			if err = l.load(m); err != nil {
+				return err
+			}
+		}
+	}
+	l.kvs = append(l.kvs, kv)
+	return nil
+}
+
+// stripIncludes returns the include directive of a YAML or JSON file,
+// removing it from the value of kv.
+func stripIncludes(kv *config.KeyValue) ([]string, error) {
+	if kv.Format != "yaml" && kv.Format != "json" {
+		return nil, nil
+	}
+	c := codec.GetCodec(kv.Format)
+	if c == nil {
+		return nil, nil
+	}
+	doc := make(map[string]interface{})
+	if err := c.Unmarshal(kv.Value, &doc); err != nil {
+		// the decoder of the config reports it
+		return nil, nil
+	}
+	v, ok := doc[includeKey]
+	if !ok {
+		return nil, nil
 	}
-	kv, err := f.loadFile(f.path)
+	var includes []string
+	switch vt := v.(type) {
+	case string:
+		includes = append(includes, vt)
+	case []interface{}:
+		for _, i := range vt {
+			s, ok := i.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid include: %v", i)
+			}
+			includes = append(includes, s)
+		}
+	default:
+		return nil, fmt.Errorf("invalid include: %v", v)
+	}
+	delete(doc, includeKey)
+	data, err := c.Marshal(doc)
 	if err != nil {
 		return nil, err
 	}
-	return []*config.KeyValue{kv}, nil
+	kv.Value = data
+	return includes, nil
+}
+
+func isGlob(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+func (f *file) Load() (kvs []*config.KeyValue, err error) {
+	kvs, _, err = f.load()
+	return
 }
 
 func (f *file) Watch() (config.Watcher, error) {
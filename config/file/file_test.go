@@ -131,11 +131,11 @@ func testWatchFile(t *testing.T, path string) {
 		t.Error(err)
 	}
 	kvs, err = watch.Next()
-	if err == nil {
+	if err != nil {
 		t.Errorf("watch.Next() error(%v)", err)
 	}
-	if kvs != nil {
-		t.Errorf("watch.Next() error(%v)", err)
+	if len(kvs) != 1 || !kvs[0].Deleted {
+		t.Errorf("expected the renamed file to be deleted, but got: %v", kvs)
 	}
 
 	err = watch.Stop()
@@ -338,3 +338,201 @@ func TestMergeDataRace(t *testing.T) {
 	close(startCh)
 	wg.Wait()
 }
+
+func TestFormats(t *testing.T) {
+	files := map[string]string{
+		"a.toml": `
+[server.grpc]
+addr = ":9000"
+timeout = "1s"
+`,
+		"b.ini": `
+; comment
+name = ini
+[server.http]
+addr = :8000 ; inline comment
+`,
+		"c.properties": `
+# comment
+server.http.timeout=2s
+log.path = C:\\logs\\app.log
+motd : hello \
+    world
+`,
+		".env": `
+TOKEN="secret"
+`,
+	}
+	path := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(path, name), []byte(data), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := config.New(config.WithSource(NewSource(path)))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	expected := map[string]string{
+		"server.grpc.addr":    ":9000",
+		"server.grpc.timeout": "1s",
+		"name":                "ini",
+		"server.http.addr":    ":8000",
+		"server.http.timeout": "2s",
+		"log.path":            `C:\logs\app.log`,
+		"motd":                "hello world",
+		"TOKEN":               "secret",
+	}
+	for key, value := range expected {
+		if v, err := c.Value(key).String(); err != nil {
+			t.Error(key, err)
+		} else if v != value {
+			t.Errorf("no expect key: %s value: %v, but got: %v", key, value, v)
+		}
+	}
+}
+
+func TestRecursiveDir(t *testing.T) {
+	path := t.TempDir()
+	files := map[string]string{
+		"a.yaml":              "include: [shared/*.yaml]\nname: a\nlevel: debug\n",
+		"shared/s.yaml":       "level: info\nshared: true\n",
+		"sub/b.json":          `{"b":1}`,
+		"sub/skip.txt":        "skipped",
+		"..2024_01_01/c.yaml": "c: 1\n",
+		".hidden/e.yaml":      "e: 1\n",
+	}
+	for name, data := range files {
+		name = filepath.Join(path, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(data), 0o666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := NewSource(path, WithRecursive(), WithGlob("*.yaml", "*.json"))
+	kvs, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, kv := range kvs {
+		keys = append(keys, kv.Key)
+	}
+	if want := []string{".hidden/e.yaml", "shared/s.yaml", "a.yaml", "sub/b.json"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("expected keys: %v, but got: %v", want, keys)
+	}
+
+	c := config.New(config.WithSource(s))
+	if err = c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if level, _ := c.Value("level").String(); level != "debug" {
+		t.Errorf("expected the including file to take precedence, but got level: %s", level)
+	}
+	if _, err = c.Value("include").String(); !errors.Is(err, config.ErrNotFound) {
+		t.Errorf("expected the include directive to be removed, but got: %v", err)
+	}
+
+	// a directory created after the watcher
+	if err = os.MkdirAll(filepath.Join(path, "new"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err = os.WriteFile(filepath.Join(path, "new", "d.yaml"), []byte("d: 1\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if d, err := c.Value("d").Int(); err == nil && d == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the file of the new directory to be loaded")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestConfigMapSwap(t *testing.T) {
+	path := t.TempDir()
+	writeVersion := func(version, data string) {
+		dir := filepath.Join(path, "..v"+version)
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(data), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		tmp := filepath.Join(path, "..data_tmp")
+		if err := os.Symlink(filepath.Base(dir), tmp); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Rename(tmp, filepath.Join(path, dataDir)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeVersion("1", "version: 1\n")
+	if err := os.Symlink(filepath.Join(dataDir, "app.yaml"), filepath.Join(path, "app.yaml")); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewSource(path).Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+	writeVersion("2", "version: 2\n")
+	kvs, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || kvs[0].Key != "app.yaml" || string(kvs[0].Value) != "version: 2\n" {
+		t.Errorf("unexpected key values: %v", kvs)
+	}
+}
+
+func TestWatchAtomicSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.yaml")
+	if err := os.WriteFile(path, []byte("v: 1\n"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewSource(path).Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	// an editor writing a temporary file in several steps and renaming it over the file
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("v: ")
+	_, _ = f.WriteString("2\n")
+	_ = f.Close()
+	if err = os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || kvs[0].Key != "app.yaml" || string(kvs[0].Value) != "v: 2\n" {
+		t.Errorf("unexpected key values: %v", kvs)
+	}
+
+	if err = os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if kvs, err = w.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || kvs[0].Key != "app.yaml" || !kvs[0].Deleted {
+		t.Errorf("expected the removed file to be deleted, but got: %v", kvs)
+	}
+}
@@ -2,9 +2,19 @@ package file
 
 import "strings"
 
+// formats maps file extensions to the names of their codecs.
+var formats = map[string]string{
+	"yml": "yaml",
+	"env": "dotenv",
+}
+
 func format(name string) string {
 	if p := strings.Split(name, "."); len(p) > 1 {
-		return p[len(p)-1]
+		ext := p[len(p)-1]
+		if f, ok := formats[ext]; ok {
+			return f
+		}
+		return ext
 	}
 	return ""
 }
@@ -1,19 +1,28 @@
 package file
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"github.com/gotechbook/pkg/config"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
 var _ config.Watcher = (*watcher)(nil)
 
+// dataDir is the symlink swapped by Kubernetes when a mounted ConfigMap is updated.
+const dataDir = "..data"
+
 type watcher struct {
-	f  *file
-	fw *fsnotify.Watcher
+	f    *file
+	fw   *fsnotify.Watcher
+	dirs map[string]struct{}
+	kvs  map[string]*config.KeyValue
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -24,44 +33,146 @@ func newWatcher(f *file) (config.Watcher, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := fw.Add(f.path); err != nil {
+	kvs, dirs, err := f.load()
+	if err != nil {
+		_ = fw.Close()
 		return nil, err
 	}
 	ctx, cancel := context.WithCancel(context.Background())
-	return &watcher{f: f, fw: fw, ctx: ctx, cancel: cancel}, nil
+	w := &watcher{f: f, fw: fw, dirs: make(map[string]struct{}), ctx: ctx, cancel: cancel}
+	if err = w.sync(kvs, dirs); err != nil {
+		_ = w.Stop()
+		return nil, err
+	}
+	return w, nil
 }
 
-func (w *watcher) Next() ([]*config.KeyValue, error) {
-	select {
-	case <-w.ctx.Done():
-		return nil, w.ctx.Err()
-	case event := <-w.fw.Events:
-		if event.Op == fsnotify.Rename {
-			if _, err := os.Stat(event.Name); err == nil || os.IsExist(err) {
-				if err := w.fw.Add(event.Name); err != nil {
-					return nil, err
-				}
-			}
+// sync watches the directories of the loaded files, including the ones created
+// after the watcher, and records the loaded key values.
+func (w *watcher) sync(kvs []*config.KeyValue, dirs map[string]struct{}) error {
+	for dir := range dirs {
+		if _, ok := w.dirs[dir]; ok {
+			continue
+		}
+		if err := w.fw.Add(dir); err != nil {
+			return err
 		}
-		fi, err := os.Stat(w.f.path)
+		w.dirs[dir] = struct{}{}
+	}
+	for dir := range w.dirs {
+		if _, ok := dirs[dir]; !ok {
+			_ = w.fw.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+	w.kvs = make(map[string]*config.KeyValue, len(kvs))
+	for _, kv := range kvs {
+		w.kvs[kv.Key] = kv
+	}
+	return nil
+}
+
+func (w *watcher) Next() ([]*config.KeyValue, error) {
+	for {
+		written, err := w.wait()
 		if err != nil {
 			return nil, err
 		}
-		path := w.f.path
-		if fi.IsDir() {
-			path = filepath.Join(w.f.path, filepath.Base(event.Name))
+		// reload the whole source, as the event may be a new directory,
+		// an included file or the ..data symlink swap of a ConfigMap.
+		kvs, dirs, err := w.f.load()
+		if errors.Is(err, fs.ErrNotExist) {
+			// the source was removed, its keys are reported as deleted
+			// and its directory is watched until it is created again.
+			kvs, dirs, err = nil, map[string]struct{}{filepath.Dir(w.f.path): {}}, nil
 		}
-		kv, err := w.f.loadFile(path)
 		if err != nil {
 			return nil, err
 		}
-		return []*config.KeyValue{kv}, nil
-	case err := <-w.fw.Errors:
-		return nil, err
+		changes := w.changes(kvs, written)
+		if err = w.sync(kvs, dirs); err != nil {
+			return nil, err
+		}
+		if len(changes) > 0 {
+			return changes, nil
+		}
+	}
+}
+
+// wait blocks until an event and then until there is no event for the debounce period,
+// so the writes of an editor, e.g. the temporary file renamed over the original one
+// by an atomic save, are reloaded once. It returns the keys of the written files.
+func (w *watcher) wait() (map[string]struct{}, error) {
+	var (
+		written = make(map[string]struct{})
+		timer   <-chan time.Time
+	)
+	for {
+		select {
+		case <-w.ctx.Done():
+			return nil, w.ctx.Err()
+		case event := <-w.fw.Events:
+			if event.Op == fsnotify.Chmod && filepath.Base(event.Name) != dataDir {
+				continue
+			}
+			written[w.key(event.Name)] = struct{}{}
+			timer = time.After(w.f.debounce)
+		case <-timer:
+			return written, nil
+		case err := <-w.fw.Errors:
+			return nil, err
+		}
+	}
+}
+
+// key returns the key of the file at path.
+func (w *watcher) key(path string) string {
+	root := w.f.path
+	if !isDir(root) {
+		root = filepath.Dir(root)
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return ""
 	}
+	return filepath.ToSlash(rel)
+}
+
+// changes returns the modified and the deleted key values, including the written files.
+// If files were added, all the key values are deleted and emitted again, so their load order is kept.
+func (w *watcher) changes(kvs []*config.KeyValue, written map[string]struct{}) []*config.KeyValue {
+	var (
+		changes []*config.KeyValue
+		added   bool
+	)
+	loaded := make(map[string]struct{}, len(kvs))
+	for _, kv := range kvs {
+		loaded[kv.Key] = struct{}{}
+		prev, ok := w.kvs[kv.Key]
+		added = added || !ok
+		_, write := written[kv.Key]
+		if !ok || write || !bytes.Equal(prev.Value, kv.Value) {
+			changes = append(changes, kv)
+		}
+	}
+	var deleted []*config.KeyValue
+	for key, kv := range w.kvs {
+		if _, ok := loaded[key]; !ok || added {
+			deleted = append(deleted, &config.KeyValue{Key: key, Format: kv.Format, Deleted: true})
+		}
+	}
+	if added {
+		return append(deleted, kvs...)
+	}
+	return append(changes, deleted...)
 }
 
 func (w *watcher) Stop() error {
 	w.cancel()
 	return w.fw.Close()
 }
+
+func isDir(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.IsDir()
+}
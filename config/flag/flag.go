@@ -0,0 +1,126 @@
+package flag
+
+import (
+	stdflag "flag"
+	"fmt"
+	"github.com/gotechbook/pkg/config"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Priority is the priority of the layer of WithLayer, the highest one.
+const Priority = math.MaxInt
+
+var _ config.Source = (*flags)(nil)
+
+type Option func(*flags)
+
+type flags struct {
+	args   []string
+	set    *stdflag.FlagSet
+	values map[string]bool
+}
+
+// WithArgs with the command-line arguments to read, os.Args[1:] by default.
+func WithArgs(args []string) Option {
+	return func(f *flags) {
+		f.args = args
+	}
+}
+
+// WithValueFlags with the flags taking the next argument as their value
+// when written without "=", e.g. "--log.level info" or "--retries -1",
+// the other flags without "=" are booleans.
+func WithValueFlags(names ...string) Option {
+	return func(f *flags) {
+		for _, name := range names {
+			f.values[name] = true
+		}
+	}
+}
+
+// WithFlagSet reads the flags explicitly set on a parsed flag.FlagSet
+// instead of the arguments, so flag defaults don't override other sources.
+func WithFlagSet(set *stdflag.FlagSet) Option {
+	return func(f *flags) {
+		f.set = set
+	}
+}
+
+// NewSource new a command-line flags source, a flag named with a dotted
+// path overrides the nested key, e.g. "--server.grpc.addr=:9000".
+// Use WithLayer so that the flags take precedence over the other sources.
+func NewSource(opts ...Option) config.Source {
+	f := &flags{args: os.Args[1:], values: make(map[string]bool)}
+	for _, o := range opts {
+		o(f)
+	}
+	return f
+}
+
+func (f *flags) Load() ([]*config.KeyValue, error) {
+	if f.set != nil {
+		var kvs []*config.KeyValue
+		f.set.Visit(func(fl *stdflag.Flag) {
+			kvs = append(kvs, &config.KeyValue{
+				Key:   fl.Name,
+				Value: []byte(fl.Value.String()),
+			})
+		})
+		return kvs, nil
+	}
+	return parse(f.args, f.values)
+}
+
+// WithLayer registers a command-line flags source with the highest priority,
+// so that the flags override the values of every other source.
+func WithLayer(opts ...Option) config.Option {
+	return config.WithLayer(NewSource(opts...), config.WithName("flag"), config.WithPriority(Priority))
+}
+
+func (f *flags) Watch() (config.Watcher, error) {
+	return newWatcher(), nil
+}
+
+// parse reads "--key=value" and "-key=value" arguments, a flag without "="
+// takes the next argument as its value if it is one of values, whatever
+// the argument, otherwise it is "true". Negative numbers are not flags.
+// Parsing stops at "--".
+func parse(args []string, values map[string]bool) ([]*config.KeyValue, error) {
+	var kvs []*config.KeyValue
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' || isNumber(arg) {
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if name == "" || name[0] == '-' || name[0] == '=' {
+			continue
+		}
+		value := "true"
+		if n, v, ok := strings.Cut(name, "="); ok {
+			name, value = n, v
+		} else if values[name] {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			value = args[i+1]
+			i++
+		}
+		kvs = append(kvs, &config.KeyValue{
+			Key:   name,
+			Value: []byte(value),
+		})
+	}
+	return kvs, nil
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -0,0 +1,74 @@
+package flag
+
+import (
+	stdflag "flag"
+	"github.com/gotechbook/pkg/config"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	args := []string{"--debug", "serve", "--server.grpc.addr=:9000", "-server.http.addr", ":8000", "--log.level", "info", "-v", "--retries", "-1", "-2", "--", "--ignored=1"}
+	expected := map[string]string{
+		"debug":            "true",
+		"server.grpc.addr": ":9000",
+		"server.http.addr": ":8000",
+		"log.level":        "info",
+		"v":                "true",
+		"retries":          "-1",
+	}
+	kvs, err := parse(args, map[string]bool{"server.http.addr": true, "log.level": true, "retries": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != len(expected) {
+		t.Fatalf("expected %d key values, but got: %d", len(expected), len(kvs))
+	}
+	for _, kv := range kvs {
+		if v, ok := expected[kv.Key]; !ok || v != string(kv.Value) {
+			t.Errorf("unexpected key: %s value: %s", kv.Key, kv.Value)
+		}
+	}
+}
+
+func TestParseMissingValue(t *testing.T) {
+	if _, err := parse([]string{"--log.level"}, map[string]bool{"log.level": true}); err == nil {
+		t.Error("expected an error for a missing value")
+	}
+}
+
+func TestConfig(t *testing.T) {
+	set := stdflag.NewFlagSet("test", stdflag.ContinueOnError)
+	set.String("server.grpc.addr", ":0", "")
+	set.String("server.http.addr", ":0", "")
+	if err := set.Parse([]string{"-server.grpc.addr=:9000"}); err != nil {
+		t.Fatal(err)
+	}
+	c := config.New(config.WithSource(NewSource(WithFlagSet(set))))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if addr, _ := c.Value("server.grpc.addr").String(); addr != ":9000" {
+		t.Errorf("expected :9000, but got: %v", addr)
+	}
+	if _, err := c.Value("server.http.addr").String(); err != config.ErrNotFound {
+		t.Errorf("expected the unset flag to be ignored, but got: %v", err)
+	}
+}
+
+func TestLayer(t *testing.T) {
+	c := config.New(
+		WithLayer(WithArgs([]string{"--a=flag"})),
+		config.WithSource(NewSource(WithArgs([]string{"--a=source", "--b=source"}))),
+	)
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if a, _ := c.Value("a").String(); a != "flag" {
+		t.Errorf("expected the flag layer to take precedence, but got: %v", a)
+	}
+	if o, ok := c.Provenance("a"); !ok || o.Source != "flag" || o.Priority != Priority {
+		t.Errorf("unexpected provenance: %+v", o)
+	}
+}
@@ -0,0 +1,193 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gotechbook/pkg/codec"
+	"github.com/gotechbook/pkg/config"
+	"github.com/gotechbook/pkg/logger"
+	"io"
+	"mime"
+	stdhttp "net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var _ config.Source = (*source)(nil)
+
+type source struct {
+	url    string
+	opts   *options
+	client *stdhttp.Client
+
+	// the document of the last fetch, which the watcher polls changes against
+	lock sync.Mutex
+	doc  *document
+}
+
+// document is a fetched config document, it is the content of the cache file.
+type document struct {
+	ETag   string `json:"etag,omitempty"`
+	Format string `json:"format"`
+	Value  []byte `json:"value"`
+}
+
+// NewSource new a http source fetching the document of the url.
+func NewSource(url string, opts ...Option) config.Source {
+	op := &options{
+		ctx:      context.Background(),
+		header:   make(stdhttp.Header),
+		interval: 30 * time.Second,
+	}
+	for _, o := range opts {
+		o(op)
+	}
+	client := op.client
+	if client == nil {
+		transport := stdhttp.DefaultTransport.(*stdhttp.Transport).Clone()
+		transport.TLSClientConfig = op.tlsConfig
+		client = &stdhttp.Client{Transport: transport, Timeout: op.longPolling + 30*time.Second}
+	}
+	return &source{url: url, opts: op, client: client}
+}
+
+func (s *source) Load() ([]*config.KeyValue, error) {
+	doc, _, err := s.fetch(s.opts.ctx, "")
+	if err != nil {
+		if doc = s.readCache(); doc == nil {
+			return nil, err
+		}
+		logger.Warnf("failed to fetch config %s, the cached document is loaded: %v", s.url, err)
+	} else {
+		s.writeCache(doc)
+	}
+	s.lock.Lock()
+	s.doc = doc
+	s.lock.Unlock()
+	return []*config.KeyValue{s.keyValue(doc)}, nil
+}
+
+func (s *source) Watch() (config.Watcher, error) {
+	return newWatcher(s), nil
+}
+
+func (s *source) keyValue(doc *document) *config.KeyValue {
+	return &config.KeyValue{
+		Key:    s.url,
+		Value:  doc.Value,
+		Format: doc.Format,
+	}
+}
+
+// fetch gets the document, it returns false if the document
+// is not modified since the given etag.
+func (s *source) fetch(ctx context.Context, etag string) (*document, bool, error) {
+	req, err := stdhttp.NewRequestWithContext(ctx, stdhttp.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	for k, v := range s.opts.header {
+		req.Header[k] = v
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+		if s.opts.longPolling > 0 {
+			req.Header.Set("Prefer", "wait="+strconv.Itoa(int(s.opts.longPolling.Seconds())))
+		}
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == stdhttp.StatusNotModified {
+		return nil, false, nil
+	}
+	if resp.StatusCode != stdhttp.StatusOK {
+		return nil, false, fmt.Errorf("failed to fetch config %s: %s", s.url, resp.Status)
+	}
+	format := s.format(resp.Header.Get("Content-Type"))
+	if format == "" {
+		return nil, false, fmt.Errorf("unknown format of config %s: %q, use WithFormat", s.url, resp.Header.Get("Content-Type"))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	return &document{
+		ETag:   resp.Header.Get("ETag"),
+		Format: format,
+		Value:  data,
+	}, true, nil
+}
+
+// format returns the format of WithFormat, of the content type
+// or of the url extension, for which a codec is registered, it returns "" if none is.
+func (s *source) format(contentType string) string {
+	if s.opts.format != "" {
+		return s.opts.format
+	}
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		// e.g. application/json, application/x-yaml, application/vnd.app+json
+		sub := mt[strings.Index(mt, "/")+1:]
+		if i := strings.LastIndex(sub, "+"); i >= 0 {
+			sub = sub[i+1:]
+		}
+		sub = strings.TrimPrefix(sub, "x-")
+		if codec.GetCodec(sub) != nil {
+			return sub
+		}
+	}
+	if u, err := url.Parse(s.url); err == nil {
+		ext := strings.TrimPrefix(path.Ext(u.Path), ".")
+		if ext == "yml" {
+			ext = "yaml"
+		}
+		if codec.GetCodec(ext) != nil {
+			return ext
+		}
+	}
+	return ""
+}
+
+func (s *source) readCache() *document {
+	if s.opts.cacheFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(s.opts.cacheFile)
+	if err != nil {
+		return nil
+	}
+	doc := new(document)
+	if err = json.Unmarshal(data, doc); err != nil {
+		logger.Errorf("failed to read config cache %s: %v", s.opts.cacheFile, err)
+		return nil
+	}
+	return doc
+}
+
+// writeCache replaces the cache file atomically, so a cold start never reads a partial file.
+func (s *source) writeCache(doc *document) {
+	if s.opts.cacheFile == "" {
+		return
+	}
+	data, err := json.Marshal(doc)
+	if err == nil {
+		tmp := s.opts.cacheFile + ".tmp"
+		if err = os.MkdirAll(filepath.Dir(tmp), 0o700); err == nil {
+			if err = os.WriteFile(tmp, data, 0o600); err == nil {
+				err = os.Rename(tmp, s.opts.cacheFile)
+			}
+		}
+	}
+	if err != nil {
+		logger.Errorf("failed to write config cache %s: %v", s.opts.cacheFile, err)
+	}
+}
@@ -0,0 +1,106 @@
+package http
+
+import (
+	"context"
+	"errors"
+	stdhttp "net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	lock sync.Mutex
+	doc  string
+	etag string
+}
+
+func (s *testServer) set(doc, etag string) {
+	s.lock.Lock()
+	s.doc, s.etag = doc, etag
+	s.lock.Unlock()
+}
+
+func (s *testServer) ServeHTTP(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(stdhttp.StatusUnauthorized)
+		return
+	}
+	s.lock.Lock()
+	doc, etag := s.doc, s.etag
+	s.lock.Unlock()
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(stdhttp.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "application/x-yaml; charset=utf-8")
+	_, _ = w.Write([]byte(doc))
+}
+
+func TestSource(t *testing.T) {
+	ts := &testServer{doc: "name: v1\n", etag: `"1"`}
+	srv := httptest.NewServer(ts)
+	cache := filepath.Join(t.TempDir(), "config.cache")
+	s := NewSource(srv.URL+"/config", WithBearerToken("token"), WithInterval(10*time.Millisecond), WithCacheFile(cache))
+
+	kvs, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 1 || kvs[0].Format != "yaml" || string(kvs[0].Value) != "name: v1\n" {
+		t.Fatalf("unexpected key values: %v", kvs)
+	}
+
+	w, err := s.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(50*time.Millisecond, func() { ts.set("name: v2\n", `"2"`) })
+	if kvs, err = w.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if string(kvs[0].Value) != "name: v2\n" {
+		t.Errorf("unexpected value: %s", kvs[0].Value)
+	}
+	if err = w.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Next(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, but got: %v", err)
+	}
+
+	// a cold start with the server down loads the cached document
+	srv.Close()
+	kvs, err = NewSource(srv.URL+"/config", WithCacheFile(cache)).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(kvs[0].Value) != "name: v2\n" || kvs[0].Format != "yaml" {
+		t.Errorf("unexpected cached key values: %v", kvs)
+	}
+	if _, err = NewSource(srv.URL + "/config").Load(); err == nil {
+		t.Error("expected an error without cache")
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	srv := httptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("name: v1\n"))
+	}))
+	defer srv.Close()
+
+	if _, err := NewSource(srv.URL + "/config").Load(); err == nil {
+		t.Error("expected an error of the unknown format")
+	}
+	kvs, err := NewSource(srv.URL+"/config", WithFormat("yaml")).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if kvs[0].Format != "yaml" {
+		t.Errorf("expected the format yaml, but got: %q", kvs[0].Format)
+	}
+}
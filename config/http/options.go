@@ -0,0 +1,89 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	stdhttp "net/http"
+	"time"
+)
+
+type Option func(o *options)
+
+type options struct {
+	ctx         context.Context
+	client      *stdhttp.Client
+	header      stdhttp.Header
+	tlsConfig   *tls.Config
+	format      string
+	interval    time.Duration
+	longPolling time.Duration
+	cacheFile   string
+}
+
+// WithContext with the context of the requests.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithClient with the http client, WithTLSConfig is ignored.
+func WithClient(c *stdhttp.Client) Option {
+	return func(o *options) {
+		o.client = c
+	}
+}
+
+// WithHeader with a header of the requests.
+func WithHeader(key, value string) Option {
+	return func(o *options) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithBearerToken authenticates the requests with the bearer token.
+func WithBearerToken(token string) Option {
+	return func(o *options) {
+		o.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithTLSConfig with the tls config of the client,
+// e.g. with a client certificate for mTLS.
+func WithTLSConfig(c *tls.Config) Option {
+	return func(o *options) {
+		o.tlsConfig = c
+	}
+}
+
+// WithFormat with the format of the document, by default it is
+// inferred from the Content-Type and then from the url extension,
+// it is required if neither has a registered codec.
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// WithInterval with the polling interval, 30s by default.
+func WithInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.interval = d
+	}
+}
+
+// WithLongPolling polls without interval, asking the server to hold the
+// requests until the document changes or the wait elapses with "Prefer: wait=<seconds>".
+func WithLongPolling(wait time.Duration) Option {
+	return func(o *options) {
+		o.longPolling = wait
+	}
+}
+
+// WithCacheFile caches the last fetched document in the file,
+// which is loaded when the server is unavailable on a cold start.
+func WithCacheFile(path string) Option {
+	return func(o *options) {
+		o.cacheFile = path
+	}
+}
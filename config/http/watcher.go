@@ -0,0 +1,73 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"github.com/gotechbook/pkg/config"
+	"time"
+)
+
+var _ config.Watcher = (*watcher)(nil)
+
+const minLongPollingInterval = time.Second
+
+type watcher struct {
+	s *source
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newWatcher(s *source) *watcher {
+	ctx, cancel := context.WithCancel(s.opts.ctx)
+	return &watcher{s: s, ctx: ctx, cancel: cancel}
+}
+
+// Next polls the document until it changes, with long polling
+// the requests are sent without interval.
+func (w *watcher) Next() ([]*config.KeyValue, error) {
+	var last time.Time
+	for {
+		wait := w.s.opts.interval
+		if w.s.opts.longPolling > 0 {
+			// guard against servers answering immediately instead of holding the request
+			wait = time.Until(last.Add(minLongPollingInterval))
+		}
+		select {
+		case <-w.ctx.Done():
+			return nil, w.ctx.Err()
+		case <-time.After(wait):
+		}
+		last = time.Now()
+		w.s.lock.Lock()
+		prev := w.s.doc
+		w.s.lock.Unlock()
+		var etag string
+		if prev != nil {
+			etag = prev.ETag
+		}
+		doc, modified, err := w.s.fetch(w.ctx, etag)
+		if err != nil {
+			if w.ctx.Err() != nil {
+				return nil, w.ctx.Err()
+			}
+			return nil, err
+		}
+		if !modified {
+			continue
+		}
+		w.s.lock.Lock()
+		w.s.doc = doc
+		w.s.lock.Unlock()
+		if prev != nil && bytes.Equal(prev.Value, doc.Value) && prev.Format == doc.Format {
+			continue
+		}
+		w.s.writeCache(doc)
+		return []*config.KeyValue{w.s.keyValue(doc)}, nil
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return nil
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	// ProfileEnv is the environment variable selecting the active profiles, e.g. CONFIG_PROFILE=dev.
+	ProfileEnv = "CONFIG_PROFILE"
+	// ProfileFlag is the command-line flag selecting the active profiles, e.g. --profile=prod.
+	ProfileFlag = "profile"
+	// BaseProfile is the profile of the sources which are always loaded.
+	BaseProfile = "base"
+)
+
+// SourceOption is a layered source option.
+type SourceOption func(*sourceLayer)
+
+// sourceLayer is a source with its name, priority and profiles.
+type sourceLayer struct {
+	source   Source
+	name     string
+	priority int
+	profiles []string
+}
+
+// WithName names the source in the provenance of its keys.
+func WithName(name string) SourceOption {
+	return func(l *sourceLayer) {
+		l.name = name
+	}
+}
+
+// WithPriority with the priority of the source, sources with a higher
+// priority take precedence, sources with the same priority are merged in order.
+func WithPriority(priority int) SourceOption {
+	return func(l *sourceLayer) {
+		l.priority = priority
+	}
+}
+
+// WithProfile loads the source only if one of the profiles is active,
+// a source without profiles or with the base profile is always loaded.
+func WithProfile(profiles ...string) SourceOption {
+	return func(l *sourceLayer) {
+		l.profiles = profiles
+	}
+}
+
+// ArrayMerge is the strategy merging an array with the array of a source taking precedence.
+type ArrayMerge struct {
+	mode int
+	key  string
+}
+
+const (
+	arrayReplace = iota
+	arrayAppend
+	arrayByKey
+)
+
+var (
+	// ArrayReplace replaces the array, it is the default strategy.
+	ArrayReplace = ArrayMerge{mode: arrayReplace}
+	// ArrayAppend appends the elements to the array.
+	ArrayAppend = ArrayMerge{mode: arrayAppend}
+)
+
+// ArrayMergeByKey merges the objects of the arrays having the same field value
+// and appends the others, e.g. ArrayMergeByKey("name") for a list of servers.
+func ArrayMergeByKey(field string) ArrayMerge {
+	return ArrayMerge{mode: arrayByKey, key: field}
+}
+
+// Origin is the source supplying the effective value of a key.
+type Origin struct {
+	// Source is the name of the source.
+	Source string
+	// Key is the key of the KeyValue, e.g. the file path.
+	Key      string
+	Priority int
+}
+
+// activeLayers returns the layers of the active profiles ordered by priority.
+func activeLayers(o options) []*sourceLayer {
+	profiles := o.profiles
+	if profiles == nil {
+		profiles = activeProfiles(os.Args[1:], os.Getenv(ProfileEnv))
+	}
+	all := make([]*sourceLayer, 0, len(o.sources)+len(o.layers))
+	for _, s := range o.sources {
+		all = append(all, &sourceLayer{source: s})
+	}
+	all = append(all, o.layers...)
+	layers := make([]*sourceLayer, 0, len(all))
+	for i, l := range all {
+		if l.name == "" {
+			l.name = fmt.Sprintf("source-%d", i)
+		}
+		if isActive(l.profiles, profiles) {
+			layers = append(layers, l)
+		}
+	}
+	sort.SliceStable(layers, func(i, j int) bool {
+		return layers[i].priority < layers[j].priority
+	})
+	return layers
+}
+
+func isActive(profiles, active []string) bool {
+	if len(profiles) == 0 {
+		return true
+	}
+	for _, p := range profiles {
+		if p == BaseProfile {
+			return true
+		}
+		for _, a := range active {
+			if p == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// activeProfiles returns the profiles of the --profile flag, or else of the env.
+func activeProfiles(args []string, env string) []string {
+	value := env
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if v, ok := strings.CutPrefix(name, ProfileFlag+"="); ok {
+			value = v
+		} else if name == ProfileFlag && i+1 < len(args) {
+			value = args[i+1]
+			i++
+		}
+	}
+	var profiles []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+// merger deep merges the documents of the layers, recording the origin of the keys.
+type merger struct {
+	arrays  map[string]ArrayMerge
+	origins map[string]Origin
+}
+
+func (m *merger) merge(dst, src map[string]interface{}, prefix string, origin Origin) {
+	for k, sv := range src {
+		path := prefix + k
+		dv, ok := dst[k]
+		switch s := sv.(type) {
+		case nil:
+			// null does not override
+			if ok {
+				continue
+			}
+			dst[k] = nil
+		case map[string]interface{}:
+			d, ok := dv.(map[string]interface{})
+			if !ok {
+				d = make(map[string]interface{}, len(s))
+				dst[k] = d
+			}
+			m.merge(d, s, path+".", origin)
+			if len(s) > 0 {
+				continue
+			}
+		case []interface{}:
+			if d, ok := dv.([]interface{}); ok {
+				dst[k] = m.mergeArray(path, d, s, origin)
+			} else {
+				dst[k] = s
+			}
+		default:
+			dst[k] = sv
+		}
+		m.origins[path] = origin
+	}
+}
+
+func (m *merger) mergeArray(path string, dst, src []interface{}, origin Origin) []interface{} {
+	strategy, ok := m.arrays[path]
+	if !ok {
+		strategy = m.arrays[""]
+	}
+	switch strategy.mode {
+	case arrayAppend:
+		return append(dst, src...)
+	case arrayByKey:
+		for _, sv := range src {
+			s, ok := sv.(map[string]interface{})
+			if !ok {
+				dst = append(dst, sv)
+				continue
+			}
+			if i := indexByKey(dst, strategy.key, s[strategy.key]); i >= 0 {
+				m.merge(dst[i].(map[string]interface{}), s, path+".", origin)
+			} else {
+				dst = append(dst, s)
+			}
+		}
+		return dst
+	default:
+		return src
+	}
+}
+
+func indexByKey(elems []interface{}, key string, value interface{}) int {
+	if value == nil {
+		return -1
+	}
+	for i, e := range elems {
+		if m, ok := e.(map[string]interface{}); ok && m[key] != nil && fmt.Sprint(m[key]) == fmt.Sprint(value) {
+			return i
+		}
+	}
+	return -1
+}
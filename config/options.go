@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gotechbook/pkg/codec"
 	"github.com/gotechbook/pkg/logger"
@@ -18,9 +19,20 @@ type Resolver func(map[string]interface{}) error
 type Option func(*options)
 
 type options struct {
-	sources  []Source
-	decoder  Decoder
-	resolver Resolver
+	sources   []Source
+	layers    []*sourceLayer
+	profiles  []string
+	arrays    map[string]ArrayMerge
+	decoder   Decoder
+	resolver  Resolver
+	secrets   map[string]SecretResolver
+	decrypter SecretResolver
+	schema    Schema
+	strict    bool
+	onError   func(error)
+	veto      Veto
+	history   int
+	maskKeys  []string
 }
 
 // WithSource with config source.
@@ -30,6 +42,39 @@ func WithSource(s ...Source) Option {
 	}
 }
 
+// WithLayer appends a source with its name, priority and profiles,
+// the sources of WithSource are merged first with the priority 0.
+func WithLayer(s Source, opts ...SourceOption) Option {
+	return func(o *options) {
+		l := &sourceLayer{source: s}
+		for _, opt := range opts {
+			opt(l)
+		}
+		o.layers = append(o.layers, l)
+	}
+}
+
+// WithProfiles with the active profiles, by default they are selected
+// by the --profile flag or the CONFIG_PROFILE env, e.g. "dev" or "prod,eu".
+func WithProfiles(profiles ...string) Option {
+	return func(o *options) {
+		o.profiles = append([]string{}, profiles...)
+	}
+}
+
+// WithArrayMerge with the strategy merging the arrays at the given key paths,
+// e.g. "servers", or every array if no path is given.
+func WithArrayMerge(m ArrayMerge, paths ...string) Option {
+	return func(o *options) {
+		if len(paths) == 0 {
+			paths = []string{""}
+		}
+		for _, p := range paths {
+			o.arrays[p] = m
+		}
+	}
+}
+
 // WithDecoder with config decoder.
 // DefaultDecoder behavior:
 // If KeyValue.Format is non-empty, then KeyValue.Value will be deserialized into map[string]interface{}
@@ -48,6 +93,75 @@ func WithResolver(r Resolver) Option {
 	}
 }
 
+// WithSecretResolver with the resolver of the ${secret:<scheme>:<ref>} placeholders,
+// the "file" and "env" schemes are resolved by FileSecret and EnvSecret by default.
+// It only applies to the default resolver.
+func WithSecretResolver(scheme string, r SecretResolver) Option {
+	return func(o *options) {
+		o.secrets[scheme] = r
+	}
+}
+
+// WithDecrypter with the decrypter of the ${enc:<ciphertext>} placeholders, e.g. an age identity.
+// It only applies to the default resolver.
+func WithDecrypter(d SecretResolver) Option {
+	return func(o *options) {
+		o.decrypter = d
+	}
+}
+
+// WithDecryptionKey decrypts the ${enc:<ciphertext>} placeholders with the AES-GCM key.
+// It only applies to the default resolver.
+func WithDecryptionKey(key []byte) Option {
+	return WithDecrypter(AESGCMDecrypter(key))
+}
+
+// WithSchema validates the config on Load and on every watched update,
+// an invalid update is rejected and the last valid config is kept.
+func WithSchema(s Schema) Option {
+	return func(o *options) {
+		o.schema = s
+	}
+}
+
+// WithStrict makes the keys unknown to the schema errors.
+func WithStrict(strict bool) Option {
+	return func(o *options) {
+		o.strict = strict
+	}
+}
+
+// WithErrorHandler with the handler of the rejected watched updates.
+func WithErrorHandler(h func(error)) Option {
+	return func(o *options) {
+		o.onError = h
+	}
+}
+
+// WithVeto with the veto of the updates, it is called before every update
+// after the first load is applied.
+func WithVeto(v Veto) Option {
+	return func(o *options) {
+		o.veto = v
+	}
+}
+
+// WithHistory with the number of previous snapshots kept for Rollback, 10 by default.
+func WithHistory(n int) Option {
+	return func(o *options) {
+		o.history = n
+	}
+}
+
+// WithMaskKeys masks the values of the keys in the change logs, the exports and
+// the admin handler, a key matches
+// a full key path such as "db.password" or a key name such as "password".
+func WithMaskKeys(keys ...string) Option {
+	return func(o *options) {
+		o.maskKeys = append(o.maskKeys, keys...)
+	}
+}
+
 // WithLogger with config logger.
 // Deprecated: use global logger instead.
 func WithLogger(l logger.Logger) Option {
@@ -80,53 +194,137 @@ func defaultDecoder(src *KeyValue, target map[string]interface{}) error {
 // defaultResolver resolve placeholder in map value,
 // placeholder format in ${key:default}.
 func defaultResolver(input map[string]interface{}) error {
-	mapper := func(name string) string {
-		args := strings.SplitN(strings.TrimSpace(name), ":", 2) //nolint:gomnd
-		if v, has := readValue(input, args[0]); has {
-			s, _ := v.String()
-			return s
-		} else if len(args) > 1 { // default value
-			return args[1]
-		}
-		return ""
-	}
-
-	var resolve func(map[string]interface{}) error
-	resolve = func(sub map[string]interface{}) error {
-		for k, v := range sub {
-			switch vt := v.(type) {
-			case string:
-				sub[k] = expand(vt, mapper)
-			case map[string]interface{}:
-				if err := resolve(vt); err != nil {
-					return err
+	return newResolver(defaultSecrets(), nil)(input)
+}
+
+func defaultSecrets() map[string]SecretResolver {
+	return map[string]SecretResolver{
+		"file": FileSecret,
+		"env":  EnvSecret,
+	}
+}
+
+// newResolver returns a resolver of the ${key:default} placeholders which also
+// resolves ${secret:<scheme>:<ref>} with secrets and ${enc:<ciphertext>} with decrypter.
+// A string containing a secret is resolved into a Secret.
+func newResolver(secrets map[string]SecretResolver, decrypter SecretResolver) Resolver {
+	return func(input map[string]interface{}) error {
+		var (
+			mapper func(string) (string, bool, error)
+			depth  int
+		)
+		mapper = func(name string) (string, bool, error) {
+			name = strings.TrimSpace(name)
+			if ref, ok := strings.CutPrefix(name, "enc:"); ok {
+				if decrypter == nil {
+					return "", false, errors.New("no decrypter of encrypted config values")
 				}
-			case []interface{}:
-				for i, iface := range vt {
-					switch it := iface.(type) {
-					case string:
-						vt[i] = expand(it, mapper)
-					case map[string]interface{}:
-						if err := resolve(it); err != nil {
-							return err
+				v, err := decrypter(ref)
+				return v, true, err
+			}
+			if ref, ok := strings.CutPrefix(name, "secret:"); ok {
+				scheme, ref, _ := strings.Cut(ref, ":")
+				r, ok := secrets[scheme]
+				if !ok {
+					return "", false, fmt.Errorf("unknown secret scheme: %s", scheme)
+				}
+				v, err := r(ref)
+				return v, true, err
+			}
+			args := strings.SplitN(name, ":", 2) //nolint:gomnd
+			if v, has := readValue(input, args[0]); has {
+				switch s := v.Load().(type) {
+				case Secret:
+					return s.Reveal(), true, nil
+				case string:
+					// the referenced value may not be resolved yet
+					if depth >= maxResolveDepth {
+						return "", false, fmt.Errorf("too deep placeholder reference: %s", args[0])
+					}
+					depth++
+					r, err := expand(s, mapper)
+					depth--
+					if err != nil {
+						return "", false, err
+					}
+					if sr, ok := r.(Secret); ok {
+						return sr.Reveal(), true, nil
+					}
+					return r.(string), false, nil
+				}
+				s, _ := v.String()
+				return s, false, nil
+			} else if len(args) > 1 { // default value
+				return args[1], false, nil
+			}
+			return "", false, nil
+		}
+
+		var resolve func(map[string]interface{}) error
+		resolve = func(sub map[string]interface{}) error {
+			for k, v := range sub {
+				switch vt := v.(type) {
+				case string:
+					s, err := expand(vt, mapper)
+					if err != nil {
+						return fmt.Errorf("failed to resolve key %s: %w", k, err)
+					}
+					sub[k] = s
+				case map[string]interface{}:
+					if err := resolve(vt); err != nil {
+						return err
+					}
+				case []interface{}:
+					for i, iface := range vt {
+						switch it := iface.(type) {
+						case string:
+							s, err := expand(it, mapper)
+							if err != nil {
+								return fmt.Errorf("failed to resolve key %s: %w", k, err)
+							}
+							vt[i] = s
+						case map[string]interface{}:
+							if err := resolve(it); err != nil {
+								return err
+							}
 						}
 					}
+					sub[k] = vt
 				}
-				sub[k] = vt
 			}
+			return nil
 		}
-		return nil
+		return resolve(input)
 	}
-	return resolve(input)
 }
 
-func expand(s string, mapping func(string) string) string {
-	r := regexp.MustCompile(`\${(.*?)}`)
-	re := r.FindAllStringSubmatch(s, -1)
-	for _, i := range re {
-		if len(i) == 2 { //nolint:gomnd
-			s = strings.ReplaceAll(s, i[0], mapping(i[1]))
+const maxResolveDepth = 32
+
+var placeholder = regexp.MustCompile(`\${(.*?)}`)
+
+// expand replaces the placeholders of s, returning a Secret if any of them is secret.
+func expand(s string, mapping func(string) (string, bool, error)) (interface{}, error) {
+	var (
+		secret bool
+		err    error
+	)
+	for _, i := range placeholder.FindAllStringSubmatch(s, -1) {
+		if len(i) != 2 { //nolint:gomnd
+			continue
+		}
+		v, isSecret, e := mapping(i[1])
+		if e != nil {
+			err = e
+			break
 		}
+		secret = secret || isSecret
+		s = strings.ReplaceAll(s, i[0], v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if secret {
+		return Secret(s), nil
 	}
-	return s
+	return s, nil
 }
@@ -1,15 +1,12 @@
 package config
 
 import (
-	"bytes"
-	"encoding/gob"
 	"encoding/json"
 	"fmt"
 	"github.com/gotechbook/pkg/logger"
+	"sort"
 	"strings"
 	"sync"
-
-	"github.com/imdario/mergo"
 )
 
 // Reader is config reader.
@@ -21,38 +18,83 @@ type Reader interface {
 }
 
 type reader struct {
-	opts   options
-	values map[string]interface{}
-	lock   sync.Mutex
+	opts    options
+	layers  map[int]*layer
+	values  map[string]interface{}
+	origins map[string]Origin
+	lock    sync.Mutex
+}
+
+// layer holds the documents decoded from one source in load order,
+// a document is replaced when its KeyValue is merged again.
+type layer struct {
+	keys []string
+	docs map[string]map[string]interface{}
 }
 
-func newReader(opts options) Reader {
+func newReader(opts options) *reader {
 	return &reader{
 		opts:   opts,
+		layers: make(map[int]*layer),
 		values: make(map[string]interface{}),
 		lock:   sync.Mutex{},
 	}
 }
 
 func (r *reader) Merge(kvs ...*KeyValue) error {
-	merged, err := r.cloneMap()
-	if err != nil {
-		return err
-	}
+	return r.merge(0, false, kvs...)
+}
+
+// merge decodes kvs into the layer of the given source and rebuilds
+// the merged values, sources with a higher index take precedence,
+// as the layers are ordered by priority.
+// If replace is true the documents previously loaded from the source are dropped.
+func (r *reader) merge(source int, replace bool, kvs ...*KeyValue) error {
+	docs := make([]map[string]interface{}, 0, len(kvs))
 	for _, kv := range kvs {
+		if kv.Deleted {
+			docs = append(docs, nil)
+			continue
+		}
 		next := make(map[string]interface{})
 		if err := r.opts.decoder(kv, next); err != nil {
 			logger.Errorf("Failed to config decode error: %v key: %s value: %s", err, kv.Key, string(kv.Value))
 			return err
 		}
-		if err := mergo.Map(&merged, convertMap(next), mergo.WithOverride); err != nil {
-			logger.Errorf("Failed to config merge error: %v key: %s value: %s", err, kv.Key, string(kv.Value))
-			return err
-		}
+		docs = append(docs, convertMap(next).(map[string]interface{}))
 	}
+
 	r.lock.Lock()
-	r.values = merged
-	r.lock.Unlock()
+	defer r.lock.Unlock()
+	next := &layer{docs: make(map[string]map[string]interface{})}
+	if l, ok := r.layers[source]; ok && !replace {
+		next.keys = append(next.keys, l.keys...)
+		for k, doc := range l.docs {
+			next.docs[k] = doc
+		}
+	}
+	for i, kv := range kvs {
+		_, ok := next.docs[kv.Key]
+		if kv.Deleted {
+			if ok {
+				delete(next.docs, kv.Key)
+				next.keys = removeKey(next.keys, kv.Key)
+			}
+			continue
+		}
+		if !ok {
+			next.keys = append(next.keys, kv.Key)
+		}
+		next.docs[kv.Key] = docs[i]
+	}
+	layers := make(map[int]*layer, len(r.layers)+1)
+	for i, l := range r.layers {
+		layers[i] = l
+	}
+	layers[source] = next
+
+	r.layers = layers
+	r.values, r.origins = r.mergeLayers(layers)
 	return nil
 }
 
@@ -74,29 +116,72 @@ func (r *reader) Resolve() error {
 	return r.opts.resolver(r.values)
 }
 
-func (r *reader) cloneMap() (map[string]interface{}, error) {
+// snapshot returns the current merged values, which must not be modified.
+func (r *reader) snapshot() map[string]interface{} {
 	r.lock.Lock()
 	defer r.lock.Unlock()
-	return cloneMap(r.values)
-}
-
-func cloneMap(src map[string]interface{}) (map[string]interface{}, error) {
-	// https://gist.github.com/soroushjp/0ec92102641ddfc3ad5515ca76405f4d
-	var buf bytes.Buffer
-	gob.Register(map[string]interface{}{})
-	gob.Register([]interface{}{})
-	enc := gob.NewEncoder(&buf)
-	dec := gob.NewDecoder(&buf)
-	err := enc.Encode(src)
-	if err != nil {
-		return nil, err
+	return r.values
+}
+
+// readerState is the state of the reader restored when an update is rejected.
+type readerState struct {
+	layers  map[int]*layer
+	values  map[string]interface{}
+	origins map[string]Origin
+}
+
+func (r *reader) state() readerState {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return readerState{layers: r.layers, values: r.values, origins: r.origins}
+}
+
+func (r *reader) restore(s readerState) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.layers, r.values, r.origins = s.layers, s.values, s.origins
+}
+
+func removeKey(keys []string, key string) []string {
+	ks := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if k != key {
+			ks = append(ks, k)
+		}
+	}
+	return ks
+}
+
+// mergeLayers deep merges the documents of the layers in order,
+// returning the merged values and the origins of their leaf keys.
+func (r *reader) mergeLayers(layers map[int]*layer) (map[string]interface{}, map[string]Origin) {
+	sources := make([]int, 0, len(layers))
+	for i := range layers {
+		sources = append(sources, i)
 	}
-	var clone map[string]interface{}
-	err = dec.Decode(&clone)
-	if err != nil {
-		return nil, err
+	sort.Ints(sources)
+	merged := make(map[string]interface{})
+	m := &merger{arrays: r.opts.arrays, origins: make(map[string]Origin)}
+	for _, i := range sources {
+		l := layers[i]
+		origin := Origin{Source: fmt.Sprintf("source-%d", i)}
+		if i < len(r.opts.layers) {
+			origin.Source, origin.Priority = r.opts.layers[i].name, r.opts.layers[i].priority
+		}
+		for _, k := range l.keys {
+			origin.Key = k
+			// copy the document, as the merged values share it
+			// and the resolver modifies them in place.
+			m.merge(merged, convertMap(l.docs[k]).(map[string]interface{}), "", origin)
+		}
+	}
+	leaves := flatten(merged)
+	for k := range m.origins {
+		if _, ok := leaves[k]; !ok {
+			delete(m.origins, k)
+		}
 	}
-	return clone, nil
+	return merged, m.origins
 }
 
 func convertMap(src interface{}) interface{} {
@@ -0,0 +1,54 @@
+package redis
+
+import "context"
+
+type Option func(o *options)
+
+type options struct {
+	ctx     context.Context
+	keys    []string
+	hash    string
+	channel string
+	format  string
+}
+
+// WithContext with the context of the redis requests.
+func WithContext(ctx context.Context) Option {
+	return func(o *options) {
+		o.ctx = ctx
+	}
+}
+
+// WithKeys loads string keys, each holding a config document
+// such as "app:config.yaml", or a single value when its format is unknown.
+func WithKeys(keys ...string) Option {
+	return func(o *options) {
+		o.keys = keys
+	}
+}
+
+// WithHash loads the fields of a hash, each field is handled
+// like a key of WithKeys, e.g. "config.yaml" or "server.addr".
+func WithHash(key string) Option {
+	return func(o *options) {
+		o.hash = key
+	}
+}
+
+// WithChannel watches changes by subscribing to a pub/sub channel,
+// any message published on it reloads the config.
+// By default keyspace notifications of the keys are used, which
+// requires notify-keyspace-events to be enabled on the server, e.g. "K$hg".
+func WithChannel(channel string) Option {
+	return func(o *options) {
+		o.channel = channel
+	}
+}
+
+// WithFormat with the format of every value, by default it is
+// inferred from the key extension when a codec is registered for it.
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
@@ -0,0 +1,145 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gotechbook/pkg/codec"
+	"github.com/gotechbook/pkg/config"
+	dbredis "github.com/gotechbook/pkg/database/redis"
+	"github.com/redis/go-redis/v9"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var _ config.Source = (*source)(nil)
+
+type source struct {
+	client *dbredis.Client
+	opts   *options
+
+	// the values of the last Load, which the watcher compares changes against
+	lock   sync.Mutex
+	values map[string][]byte
+}
+
+// NewSource new a redis source.
+func NewSource(client *dbredis.Client, opts ...Option) (config.Source, error) {
+	op := &options{
+		ctx: context.Background(),
+	}
+	for _, o := range opts {
+		o(op)
+	}
+	if len(op.keys) == 0 && op.hash == "" {
+		return nil, errors.New("redis config keys and hash are empty")
+	}
+	return &source{client: client, opts: op}, nil
+}
+
+func (s *source) Load() ([]*config.KeyValue, error) {
+	values, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	s.lock.Lock()
+	s.values = values
+	s.lock.Unlock()
+	return s.keyValues(values), nil
+}
+
+func (s *source) Watch() (config.Watcher, error) {
+	var pubsub *redis.PubSub
+	if s.opts.channel != "" {
+		pubsub = s.client.Db.Subscribe(s.opts.ctx, s.opts.channel)
+	} else {
+		patterns := make([]string, 0, len(s.opts.keys)+1)
+		for _, k := range s.opts.keys {
+			patterns = append(patterns, "__keyspace@*__:"+k)
+		}
+		if s.opts.hash != "" {
+			patterns = append(patterns, "__keyspace@*__:"+s.opts.hash)
+		}
+		pubsub = s.client.Db.PSubscribe(s.opts.ctx, patterns...)
+	}
+	// wait for the subscription, so no change made after Watch is missed
+	if _, err := pubsub.Receive(s.opts.ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+	s.lock.Lock()
+	values := s.values
+	s.lock.Unlock()
+	return newWatcher(s, pubsub, values), nil
+}
+
+// load reads the raw values of the keys and of the hash fields.
+func (s *source) load() (map[string][]byte, error) {
+	values := make(map[string][]byte)
+	for _, k := range s.opts.keys {
+		v, err := s.client.Db.Get(s.opts.ctx, k).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[k] = v
+	}
+	if s.opts.hash != "" {
+		fields, err := s.client.Db.HGetAll(s.opts.ctx, s.opts.hash).Result()
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fields {
+			values[k] = []byte(v)
+		}
+	}
+	return values, nil
+}
+
+func (s *source) keyValues(values map[string][]byte) []*config.KeyValue {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	kvs := make([]*config.KeyValue, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, &config.KeyValue{
+			Key:    k,
+			Value:  values[k],
+			Format: s.format(k),
+		})
+	}
+	return kvs
+}
+
+// changes returns the key values which differ between prev and next,
+// keys missing in next are reported as deleted.
+func (s *source) changes(prev, next map[string][]byte) []*config.KeyValue {
+	changed := make(map[string][]byte)
+	for k, v := range next {
+		if p, ok := prev[k]; !ok || !bytes.Equal(p, v) {
+			changed[k] = v
+		}
+	}
+	kvs := s.keyValues(changed)
+	for k := range prev {
+		if _, ok := next[k]; !ok {
+			kvs = append(kvs, &config.KeyValue{Key: k, Format: s.format(k), Deleted: true})
+		}
+	}
+	return kvs
+}
+
+func (s *source) format(key string) string {
+	if s.opts.format != "" {
+		return s.opts.format
+	}
+	if i := strings.LastIndexByte(key, '.'); i != -1 && codec.GetCodec(key[i+1:]) != nil {
+		return key[i+1:]
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package redis
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gotechbook/pkg/config"
+	dbredis "github.com/gotechbook/pkg/database/redis"
+	"github.com/redis/go-redis/v9"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := &dbredis.Client{Db: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+	defer client.Db.Close()
+
+	mr.Set("app:config.json", `{"server":{"addr":":8000"}}`)
+	mr.HSet("app:overrides", "server.timeout", "1s", "name", "test")
+
+	s, err := NewSource(client, WithKeys("app:config.json", "app:missing.json"), WithHash("app:overrides"), WithChannel("app:config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kvs, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 3 || kvs[0].Key != "app:config.json" || kvs[0].Format != "json" || kvs[2].Key != "server.timeout" || kvs[2].Format != "" {
+		t.Fatalf("unexpected key values: %+v", kvs)
+	}
+
+	w, err := s.Watch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	mr.Set("app:config.json", `{"server":{"addr":":9000"}}`)
+	mr.HDel("app:overrides", "name")
+	mr.Publish("app:config", "app:config.json")
+	kvs, err = w.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kvs) != 2 {
+		t.Fatalf("unexpected key values: %+v", kvs)
+	}
+	if kvs[0].Key != "app:config.json" || string(kvs[0].Value) != `{"server":{"addr":":9000"}}` {
+		t.Errorf("unexpected changed key value: %+v", kvs[0])
+	}
+	if kvs[1].Key != "name" || !kvs[1].Deleted {
+		t.Errorf("unexpected deleted key value: %+v", kvs[1])
+	}
+}
+
+func TestConfig(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := &dbredis.Client{Db: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+	defer client.Db.Close()
+
+	mr.Set("app:config.json", `{"server":{"addr":":8000"}}`)
+	s, err := NewSource(client, WithKeys("app:config.json"), WithChannel("app:config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := config.New(config.WithSource(s))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ch := make(chan string, 1)
+	if err := c.Watch("server.addr", func(_ string, v config.Value) {
+		addr, _ := v.String()
+		ch <- addr
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mr.Set("app:config.json", `{"server":{"addr":":9000"}}`)
+	if err := client.Db.Publish(context.Background(), "app:config", "app:config.json").Err(); err != nil {
+		t.Fatal(err)
+	}
+	if addr := <-ch; addr != ":9000" {
+		t.Errorf("expected :9000, but got: %v", addr)
+	}
+}
@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"github.com/gotechbook/pkg/config"
+	"github.com/redis/go-redis/v9"
+)
+
+var _ config.Watcher = (*watcher)(nil)
+
+type watcher struct {
+	source *source
+	pubsub *redis.PubSub
+	ch     <-chan *redis.Message
+	values map[string][]byte
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newWatcher(s *source, pubsub *redis.PubSub, values map[string][]byte) *watcher {
+	w := &watcher{
+		source: s,
+		pubsub: pubsub,
+		ch:     pubsub.Channel(),
+		values: values,
+	}
+	w.ctx, w.cancel = context.WithCancel(s.opts.ctx)
+	return w
+}
+
+func (w *watcher) Next() ([]*config.KeyValue, error) {
+	for {
+		select {
+		case <-w.ctx.Done():
+			return nil, w.ctx.Err()
+		case _, ok := <-w.ch:
+			if !ok {
+				if w.ctx.Err() != nil {
+					return nil, w.ctx.Err()
+				}
+				return nil, errors.New("redis config subscription closed")
+			}
+			w.drain()
+			values, err := w.source.load()
+			if err != nil {
+				return nil, err
+			}
+			kvs := w.source.changes(w.values, values)
+			w.values = values
+			if len(kvs) > 0 {
+				return kvs, nil
+			}
+		}
+	}
+}
+
+// drain discards pending notifications, as they are covered by a single reload.
+func (w *watcher) drain() {
+	for {
+		select {
+		case <-w.ch:
+		default:
+			return
+		}
+	}
+}
+
+func (w *watcher) Stop() error {
+	w.cancel()
+	return w.pubsub.Close()
+}
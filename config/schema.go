@@ -0,0 +1,449 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// ErrInvalid is returned when the config does not match its schema.
+var ErrInvalid = errors.New("invalid config")
+
+// Schema validates the merged config values,
+// unknown keys are errors in strict mode. The string values, such as
+// the values of the env and flag sources, are converted to the types
+// of the schema before they are validated, e.g. "9000" to an integer.
+type Schema interface {
+	Validate(values map[string]interface{}, strict bool) error
+}
+
+var (
+	_ Schema = (*jsonSchema)(nil)
+	_ Schema = (*structSchema)(nil)
+)
+
+type jsonSchema struct {
+	doc    map[string]interface{}
+	schema *jsonschema.Schema
+	strict *jsonschema.Schema
+}
+
+// NewJSONSchema returns a Schema of the JSON Schema document, in strict mode
+// the objects declaring properties do not allow additional properties unless
+// additionalProperties is set explicitly or the object is composed, i.e. it uses
+// or is a part of allOf, anyOf, oneOf or $ref.
+func NewJSONSchema(data []byte) (Schema, error) {
+	var doc, strictDoc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &strictDoc); err != nil {
+		return nil, err
+	}
+	schema, err := compileSchema(data)
+	if err != nil {
+		return nil, err
+	}
+	strictData, err := json.Marshal(strictSchema(strictDoc, "#", composedRefs(strictDoc, nil)))
+	if err != nil {
+		return nil, err
+	}
+	strict, err := compileSchema(strictData)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonSchema{doc: doc, schema: schema, strict: strict}, nil
+}
+
+func compileSchema(data []byte) (*jsonschema.Schema, error) {
+	const url = "mem:///config.schema.json"
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(url, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return c.Compile(url)
+}
+
+// compositions are the keywords composing schemas.
+var compositions = []string{"allOf", "anyOf", "oneOf"}
+
+// composedRefs returns the $ref of the parts of the compositions of doc.
+func composedRefs(doc interface{}, refs map[string]bool) map[string]bool {
+	if refs == nil {
+		refs = make(map[string]bool)
+	}
+	switch m := doc.(type) {
+	case map[string]interface{}:
+		for _, k := range compositions {
+			parts, _ := m[k].([]interface{})
+			for _, part := range parts {
+				if p, ok := part.(map[string]interface{}); ok {
+					if ref, ok := p["$ref"].(string); ok {
+						refs[ref] = true
+					}
+				}
+			}
+		}
+		for _, v := range m {
+			composedRefs(v, refs)
+		}
+	case []interface{}:
+		for _, v := range m {
+			composedRefs(v, refs)
+		}
+	}
+	return refs
+}
+
+// strictSchema disallows additional properties of the objects declaring properties
+// which are not composed, ptr is the JSON pointer of doc.
+func strictSchema(doc interface{}, ptr string, composed map[string]bool) interface{} {
+	switch m := doc.(type) {
+	case map[string]interface{}:
+		for k, v := range m {
+			if isComposition(k) {
+				// the parts of a composition are validated separately,
+				// so none of them may disallow the properties of the others
+				parts, _ := v.([]interface{})
+				for i, part := range parts {
+					if p, ok := part.(map[string]interface{}); ok {
+						for pk, pv := range p {
+							p[pk] = strictSchema(pv, ptr+"/"+k+"/"+strconv.Itoa(i)+"/"+escapePointer(pk), composed)
+						}
+					}
+				}
+				continue
+			}
+			m[k] = strictSchema(v, ptr+"/"+escapePointer(k), composed)
+		}
+		if _, ok := m["properties"]; !ok || composed[ptr] || isComposed(m) {
+			return doc
+		}
+		if _, ok := m["additionalProperties"]; !ok {
+			m["additionalProperties"] = false
+		}
+	case []interface{}:
+		for i, v := range m {
+			m[i] = strictSchema(v, ptr+"/"+strconv.Itoa(i), composed)
+		}
+	}
+	return doc
+}
+
+func isComposition(keyword string) bool {
+	for _, k := range compositions {
+		if k == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+func isComposed(schema map[string]interface{}) bool {
+	if _, ok := schema["$ref"]; ok {
+		return true
+	}
+	for _, k := range compositions {
+		if _, ok := schema[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
+
+func (s *jsonSchema) Validate(values map[string]interface{}, strict bool) error {
+	data, err := json.Marshal(revealSecrets(values))
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	doc = s.coerce([]map[string]interface{}{s.doc}, doc)
+	if strict {
+		return s.strict.Validate(doc)
+	}
+	return s.schema.Validate(doc)
+}
+
+// coerce converts the strings of v to the types of its schemas,
+// following the properties, items, compositions and local $ref.
+func (s *jsonSchema) coerce(schemas []map[string]interface{}, v interface{}) interface{} {
+	schemas = s.expand(schemas, 0)
+	switch vt := v.(type) {
+	case string:
+		var types []string
+		for _, schema := range schemas {
+			switch t := schema["type"].(type) {
+			case string:
+				types = append(types, t)
+			case []interface{}:
+				for _, e := range t {
+					if es, ok := e.(string); ok {
+						types = append(types, es)
+					}
+				}
+			}
+		}
+		return coerceString(vt, types)
+	case map[string]interface{}:
+		for k, e := range vt {
+			var children []map[string]interface{}
+			for _, schema := range schemas {
+				props, _ := schema["properties"].(map[string]interface{})
+				if p, ok := props[k].(map[string]interface{}); ok {
+					children = append(children, p)
+				} else if p, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+					children = append(children, p)
+				}
+			}
+			vt[k] = s.coerce(children, e)
+		}
+	case []interface{}:
+		var children []map[string]interface{}
+		for _, schema := range schemas {
+			if items, ok := schema["items"].(map[string]interface{}); ok {
+				children = append(children, items)
+			}
+		}
+		for i, e := range vt {
+			vt[i] = s.coerce(children, e)
+		}
+	}
+	return v
+}
+
+// expand adds the parts of the compositions and the targets of the local $ref of the schemas.
+func (s *jsonSchema) expand(schemas []map[string]interface{}, depth int) []map[string]interface{} {
+	if depth > 32 {
+		return schemas
+	}
+	var parts []map[string]interface{}
+	for _, schema := range schemas {
+		if ref, ok := schema["$ref"].(string); ok {
+			if target := s.resolve(ref); target != nil {
+				parts = append(parts, target)
+			}
+		}
+		for _, k := range compositions {
+			list, _ := schema[k].([]interface{})
+			for _, part := range list {
+				if p, ok := part.(map[string]interface{}); ok {
+					parts = append(parts, p)
+				}
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return schemas
+	}
+	return append(schemas, s.expand(parts, depth+1)...)
+}
+
+// resolve returns the schema of a local $ref such as "#/definitions/server".
+func (s *jsonSchema) resolve(ref string) map[string]interface{} {
+	if !strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	var cur interface{} = s.doc
+	for _, tok := range strings.Split(strings.TrimPrefix(ref, "#"), "/")[1:] {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		cur = m[tok]
+	}
+	m, _ := cur.(map[string]interface{})
+	return m
+}
+
+// coerceString converts s to the first of the types it can be converted to,
+// it is kept as is if it may be a string.
+func coerceString(s string, types []string) interface{} {
+	for _, t := range types {
+		if t == "string" {
+			return s
+		}
+	}
+	for _, t := range types {
+		switch t {
+		case "integer":
+			if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+				return n
+			}
+		case "number":
+			if n, err := strconv.ParseFloat(s, 64); err == nil {
+				return n
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+	}
+	return s
+}
+
+type structSchema struct {
+	typ reflect.Type
+}
+
+// NewStructSchema returns a Schema checking that the config can be scanned into v,
+// in strict mode keys without a matching struct field are errors.
+func NewStructSchema(v interface{}) Schema {
+	typ := reflect.TypeOf(v)
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return &structSchema{typ: typ}
+}
+
+func (s *structSchema) Validate(values map[string]interface{}, strict bool) error {
+	data, err := json.Marshal(revealSecrets(values))
+	if err != nil {
+		return err
+	}
+	var doc interface{}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if data, err = json.Marshal(coerceType(s.typ, doc)); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(reflect.New(s.typ).Interface())
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// coerceType converts the strings of v to the kinds of the fields of typ,
+// the durations are parsed as by Value.Duration, e.g. "5s".
+func coerceType(typ reflect.Type, v interface{}) interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch vt := v.(type) {
+	case string:
+		if typ == durationType {
+			dv := &atomicValue{}
+			dv.Store(vt)
+			if d, err := dv.Duration(); err == nil {
+				return int64(d)
+			}
+			return v
+		}
+		switch typ.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n, err := strconv.ParseInt(vt, 10, typ.Bits()); err == nil {
+				return n
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n, err := strconv.ParseUint(vt, 10, typ.Bits()); err == nil {
+				return n
+			}
+		case reflect.Float32, reflect.Float64:
+			if n, err := strconv.ParseFloat(vt, typ.Bits()); err == nil {
+				return n
+			}
+		case reflect.Bool:
+			if b, err := strconv.ParseBool(vt); err == nil {
+				return b
+			}
+		}
+	case map[string]interface{}:
+		switch typ.Kind() {
+		case reflect.Map:
+			for k, e := range vt {
+				vt[k] = coerceType(typ.Elem(), e)
+			}
+		case reflect.Struct:
+			fields := jsonFields(typ, nil)
+			for k, e := range vt {
+				f, ok := fields[k]
+				if !ok {
+					// encoding/json matches the field names case-insensitively
+					for name, field := range fields {
+						if strings.EqualFold(name, k) {
+							f, ok = field, true
+							break
+						}
+					}
+				}
+				if ok {
+					vt[k] = coerceType(f, e)
+				}
+			}
+		}
+	case []interface{}:
+		if typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array {
+			for i, e := range vt {
+				vt[i] = coerceType(typ.Elem(), e)
+			}
+		}
+	}
+	return v
+}
+
+// jsonFields returns the types of the fields of the struct by their json names,
+// including the fields of the embedded structs which are not shadowed.
+func jsonFields(typ reflect.Type, fields map[string]reflect.Type) map[string]reflect.Type {
+	if fields == nil {
+		fields = make(map[string]reflect.Type)
+	}
+	var embedded []reflect.Type
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		ft := f.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			embedded = append(embedded, ft)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if _, ok := fields[name]; !ok {
+			fields[name] = f.Type
+		}
+	}
+	for _, e := range embedded {
+		jsonFields(e, fields)
+	}
+	return fields
+}
+
+// validate checks values against the schema of the options.
+func validate(o options, values map[string]interface{}) error {
+	if o.schema == nil {
+		return nil
+	}
+	if err := o.schema.Validate(values, o.strict); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const secretMask = "******"
+
+// Secret is a value resolved from a secret placeholder, it is masked
+// when printed or marshaled, so the plaintext never appears in
+// Reader.Source dumps or logs. Value accessors return the plaintext.
+type Secret string
+
+// Reveal returns the plaintext of the secret.
+func (s Secret) Reveal() string { return string(s) }
+
+func (s Secret) String() string                 { return secretMask }
+func (s Secret) GoString() string               { return secretMask }
+func (s Secret) MarshalText() ([]byte, error)   { return []byte(secretMask), nil }
+func (s Secret) MarshalBinary() ([]byte, error) { return []byte(secretMask), nil }
+
+// SecretResolver returns the plaintext of a secret reference.
+type SecretResolver func(ref string) (string, error)
+
+// FileSecret resolves ${secret:file:/run/secrets/db} into the content of the file.
+func FileSecret(ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// EnvSecret resolves ${secret:env:DB_PASS} into the environment variable.
+func EnvSecret(ref string) (string, error) {
+	if v, ok := os.LookupEnv(ref); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("secret env %s is not set", ref)
+}
+
+// AESGCMDecrypter resolves ${enc:...} values encrypted by EncryptSecret with
+// the AES-GCM key, which must be 16, 24 or 32 bytes long.
+func AESGCMDecrypter(key []byte) SecretResolver {
+	return func(ref string) (string, error) {
+		data, err := base64.StdEncoding.DecodeString(ref)
+		if err != nil {
+			return "", err
+		}
+		gcm, err := newGCM(key)
+		if err != nil {
+			return "", err
+		}
+		if len(data) < gcm.NonceSize() {
+			return "", errors.New("encrypted secret is too short")
+		}
+		nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+		plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return "", err
+		}
+		return string(plaintext), nil
+	}
+}
+
+// EncryptSecret encrypts the plaintext with the AES-GCM key,
+// the result is used in config as ${enc:<result>}.
+func EncryptSecret(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// revealSecrets returns a copy of src with the plaintext of its secrets.
+func revealSecrets(src interface{}) interface{} {
+	switch m := src.(type) {
+	case Secret:
+		return m.Reveal()
+	case map[string]interface{}:
+		dst := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			dst[k] = revealSecrets(v)
+		}
+		return dst
+	case []interface{}:
+		dst := make([]interface{}, len(m))
+		for k, v := range m {
+			dst[k] = revealSecrets(v)
+		}
+		return dst
+	default:
+		return src
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSecret(t *testing.T) {
+	key := []byte("0123456789abcdef")
+	enc, err := EncryptSecret(key, "s3cr3t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_SECRET_TOKEN", "t0ken")
+	c := New(
+		WithSource(newTestSource(`{"db":{"password":"${enc:`+enc+`}","dsn":"root:${db.password}@tcp"},"token":"${secret:env:TEST_SECRET_TOKEN}"}`)),
+		WithDecryptionKey(key),
+	)
+	if err = c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if s, _ := c.Value("db.password").String(); s != "s3cr3t" {
+		t.Errorf("expected s3cr3t, but got: %s", s)
+	}
+	if s, _ := c.Value("db.dsn").String(); s != "root:s3cr3t@tcp" {
+		t.Errorf("expected root:s3cr3t@tcp, but got: %s", s)
+	}
+	var v struct {
+		Token string `json:"token"`
+	}
+	if err = c.Scan(&v); err != nil || v.Token != "t0ken" {
+		t.Errorf("expected t0ken, but got: %s %v", v.Token, err)
+	}
+	data, err := c.(*config).reader.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("s3cr3t")) || bytes.Contains(data, []byte("t0ken")) {
+		t.Errorf("secrets leaked in source: %s", data)
+	}
+
+	c = New(WithSource(newTestSource(`{"password":"${enc:` + enc + `}"}`)))
+	if err = c.Load(); err == nil {
+		t.Error("expected an error without a decryption key")
+	}
+}
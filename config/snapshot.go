@@ -0,0 +1,121 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrVetoed is returned when an update is vetoed.
+	ErrVetoed = errors.New("config update vetoed")
+	// ErrNoHistory is returned when there is no snapshot to roll back to.
+	ErrNoHistory = errors.New("no previous config snapshot")
+)
+
+// Veto is called with the current and the next snapshot before an update is applied,
+// returning an error rejects the update.
+type Veto func(prev, next *Snapshot) error
+
+// Snapshot is an immutable version of the config,
+// its values are consistent across keys.
+type Snapshot struct {
+	version  uint64
+	time     time.Time
+	values   map[string]interface{}
+	origins  map[string]Origin
+	maskKeys []string
+}
+
+// revision is a snapshot with the reader state it was built from.
+type revision struct {
+	state    readerState
+	snapshot *Snapshot
+}
+
+// Version returns the version of the snapshot, it increases with every applied update.
+func (s *Snapshot) Version() uint64 { return s.version }
+
+// Time returns the time the snapshot was applied.
+func (s *Snapshot) Time() time.Time { return s.time }
+
+// Value returns the value of key in the snapshot.
+func (s *Snapshot) Value(key string) Value {
+	if v, ok := readValue(s.values, key); ok {
+		return v
+	}
+	return &errValue{err: ErrNotFound}
+}
+
+// Scan unmarshals the snapshot values into v.
+func (s *Snapshot) Scan(v interface{}) error {
+	data, err := json.Marshal(revealSecrets(s.values))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// MarshalJSON marshals the snapshot with its secrets and the keys of WithMaskKeys masked.
+func (s *Snapshot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Version uint64      `json:"version"`
+		Time    time.Time   `json:"time"`
+		Values  interface{} `json:"values"`
+	}{s.version, s.time, maskValues(s.values, "", newMasks(s.maskKeys))})
+}
+
+// AdminOption is admin handler option.
+type AdminOption func(*adminOptions)
+
+type adminOptions struct {
+	auth func(r *http.Request) error
+}
+
+// WithAdminAuth with the authorization of the admin requests, a request
+// is rejected with 401 Unauthorized when auth returns an error.
+func WithAdminAuth(auth func(r *http.Request) error) AdminOption {
+	return func(o *adminOptions) {
+		o.auth = auth
+	}
+}
+
+// NewAdminHandler returns a handler serving the current snapshot masked on GET
+// and rolling back to the previous snapshot on POST to a path ending with "/rollback".
+// The handler must be protected, rollbacks are forbidden without WithAdminAuth.
+func NewAdminHandler(c Config, opts ...AdminOption) http.Handler {
+	o := &adminOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if o.auth != nil {
+			if err := o.auth(r); err != nil {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			}
+		}
+		switch {
+		case r.Method == http.MethodGet:
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/rollback"):
+			if o.auth == nil {
+				http.Error(w, "rollback requires the admin authorization", http.StatusForbidden)
+				return
+			}
+			if err := c.Rollback(); errors.Is(err, ErrNoHistory) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		default:
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(c.Snapshot())
+	})
+}
@@ -5,6 +5,9 @@ type KeyValue struct {
 	Key    string
 	Value  []byte
 	Format string
+	// Deleted reports the key has been removed from the source,
+	// merging it drops the values the key supplied before.
+	Deleted bool
 }
 
 // Source is config source.
@@ -3,8 +3,11 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"net/url"
 	"reflect"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -21,9 +24,15 @@ type Value interface {
 	Float() (float64, error)
 	String() (string, error)
 	Duration() (time.Duration, error)
+	Time() (time.Time, error)
+	Size() (int64, error)
+	URL() (*url.URL, error)
 	Slice() ([]Value, error)
+	Strings() ([]string, error)
 	Map() (map[string]Value, error)
+	StringMap() (map[string]string, error)
 	Scan(interface{}) error
+	Default(interface{}) Value
 	Load() interface{}
 	Store(interface{})
 }
@@ -32,12 +41,36 @@ type atomicValue struct {
 	atomic.Value
 }
 
+// valueBox allows an atomicValue to change the type of the value it holds.
+type valueBox struct {
+	v interface{}
+}
+
+func (v *atomicValue) Load() interface{} {
+	if b, ok := v.Value.Load().(valueBox); ok {
+		return b.v
+	}
+	return nil
+}
+
+func (v *atomicValue) Store(val interface{}) {
+	v.Value.Store(valueBox{v: val})
+}
+
+// plain returns the value, with the plaintext of a Secret.
+func (v *atomicValue) plain() interface{} {
+	if s, ok := v.Load().(Secret); ok {
+		return s.Reveal()
+	}
+	return v.Load()
+}
+
 func (v *atomicValue) typeAssertError() error {
 	return fmt.Errorf("type assert to %v failed", reflect.TypeOf(v.Load()))
 }
 
 func (v *atomicValue) Bool() (bool, error) {
-	switch val := v.Load().(type) {
+	switch val := v.plain().(type) {
 	case bool:
 		return val, nil
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, string:
@@ -47,7 +80,7 @@ func (v *atomicValue) Bool() (bool, error) {
 }
 
 func (v *atomicValue) Int() (int64, error) {
-	switch val := v.Load().(type) {
+	switch val := v.plain().(type) {
 	case int:
 		return int64(val), nil
 	case int8:
@@ -107,7 +140,7 @@ func (v *atomicValue) Map() (map[string]Value, error) {
 }
 
 func (v *atomicValue) Float() (float64, error) {
-	switch val := v.Load().(type) {
+	switch val := v.plain().(type) {
 	case int:
 		return float64(val), nil
 	case int8:
@@ -139,7 +172,7 @@ func (v *atomicValue) Float() (float64, error) {
 }
 
 func (v *atomicValue) String() (string, error) {
-	switch val := v.Load().(type) {
+	switch val := v.plain().(type) {
 	case string:
 		return val, nil
 	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
@@ -152,7 +185,18 @@ func (v *atomicValue) String() (string, error) {
 	return "", v.typeAssertError()
 }
 
+// Duration accepts Go duration strings such as "1m30s",
+// integers are read as nanoseconds.
 func (v *atomicValue) Duration() (time.Duration, error) {
+	switch val := v.plain().(type) {
+	case time.Duration:
+		return val, nil
+	case string:
+		if d, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return time.Duration(d), nil
+		}
+		return time.ParseDuration(val)
+	}
 	val, err := v.Int()
 	if err != nil {
 		return 0, err
@@ -160,25 +204,156 @@ func (v *atomicValue) Duration() (time.Duration, error) {
 	return time.Duration(val), nil
 }
 
+// Time accepts time.Time values and RFC3339 strings.
+func (v *atomicValue) Time() (time.Time, error) {
+	switch val := v.plain().(type) {
+	case time.Time:
+		return val, nil
+	case string:
+		return time.Parse(time.RFC3339, val)
+	}
+	return time.Time{}, v.typeAssertError()
+}
+
+// Size accepts byte sizes such as "512KiB" or "10MB",
+// integers are read as bytes.
+func (v *atomicValue) Size() (int64, error) {
+	if val, ok := v.plain().(string); ok {
+		return parseSize(val)
+	}
+	return v.Int()
+}
+
+func (v *atomicValue) URL() (*url.URL, error) {
+	val, err := v.String()
+	if err != nil {
+		return nil, err
+	}
+	return url.Parse(val)
+}
+
+// Strings accepts lists of scalars and comma separated strings.
+func (v *atomicValue) Strings() ([]string, error) {
+	if val, ok := v.plain().(string); ok {
+		if val == "" {
+			return []string{}, nil
+		}
+		ss := strings.Split(val, ",")
+		for i, s := range ss {
+			ss[i] = strings.TrimSpace(s)
+		}
+		return ss, nil
+	}
+	vals, err := v.Slice()
+	if err != nil {
+		return nil, err
+	}
+	ss := make([]string, 0, len(vals))
+	for _, val := range vals {
+		s, err := val.String()
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, s)
+	}
+	return ss, nil
+}
+
+func (v *atomicValue) StringMap() (map[string]string, error) {
+	vals, err := v.Map()
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[string]string, len(vals))
+	for key, val := range vals {
+		s, err := val.String()
+		if err != nil {
+			return nil, err
+		}
+		m[key] = s
+	}
+	return m, nil
+}
+
+// Default returns a Value holding def if v is null.
+func (v *atomicValue) Default(def interface{}) Value {
+	if v.Load() != nil {
+		return v
+	}
+	return newValue(def)
+}
+
 func (v *atomicValue) Scan(obj interface{}) error {
-	data, err := json.Marshal(v.Load())
+	data, err := json.Marshal(revealSecrets(v.Load()))
 	if err != nil {
 		return err
 	}
 	return json.Unmarshal(data, obj)
 }
 
+func newValue(val interface{}) Value {
+	av := &atomicValue{}
+	av.Store(val)
+	return av
+}
+
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+// parseSize parses a byte size, KB, MB... are powers of 1000
+// while KiB, MiB... are powers of 1024.
+func parseSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	unit, ok := sizeUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %q", s)
+	}
+	size := n * unit
+	if size > math.MaxInt64 {
+		return 0, fmt.Errorf("size %q overflows int64", s)
+	}
+	return int64(size), nil
+}
+
 type errValue struct {
 	err error
 }
 
-func (v errValue) Bool() (bool, error)              { return false, v.err }
-func (v errValue) Int() (int64, error)              { return 0, v.err }
-func (v errValue) Float() (float64, error)          { return 0.0, v.err }
-func (v errValue) Duration() (time.Duration, error) { return 0, v.err }
-func (v errValue) String() (string, error)          { return "", v.err }
-func (v errValue) Scan(interface{}) error           { return v.err }
-func (v errValue) Load() interface{}                { return nil }
-func (v errValue) Store(interface{})                {}
-func (v errValue) Slice() ([]Value, error)          { return nil, v.err }
-func (v errValue) Map() (map[string]Value, error)   { return nil, v.err }
+func (v errValue) Bool() (bool, error)                   { return false, v.err }
+func (v errValue) Int() (int64, error)                   { return 0, v.err }
+func (v errValue) Float() (float64, error)               { return 0.0, v.err }
+func (v errValue) Duration() (time.Duration, error)      { return 0, v.err }
+func (v errValue) Time() (time.Time, error)              { return time.Time{}, v.err }
+func (v errValue) Size() (int64, error)                  { return 0, v.err }
+func (v errValue) URL() (*url.URL, error)                { return nil, v.err }
+func (v errValue) String() (string, error)               { return "", v.err }
+func (v errValue) Scan(interface{}) error                { return v.err }
+func (v errValue) Load() interface{}                     { return nil }
+func (v errValue) Store(interface{})                     {}
+func (v errValue) Slice() ([]Value, error)               { return nil, v.err }
+func (v errValue) Strings() ([]string, error)            { return nil, v.err }
+func (v errValue) Map() (map[string]Value, error)        { return nil, v.err }
+func (v errValue) StringMap() (map[string]string, error) { return nil, v.err }
+func (v errValue) Default(def interface{}) Value         { return newValue(def) }
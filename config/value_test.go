@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestValueDuration(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want time.Duration
+	}{
+		{10000, 10000},
+		{"10000", 10000},
+		{float64(1000), 1000},
+		{"1m30s", 90 * time.Second},
+		{"250ms", 250 * time.Millisecond},
+	}
+	for _, test := range tests {
+		got, err := newValue(test.in).Duration()
+		if err != nil {
+			t.Errorf("Duration(%v) error: %v", test.in, err)
+		} else if got != test.want {
+			t.Errorf("Duration(%v) expected: %v, but got: %v", test.in, test.want, got)
+		}
+	}
+	if _, err := newValue("ten seconds").Duration(); err == nil {
+		t.Error("expected an error for an invalid duration")
+	}
+}
+
+func TestValueSize(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want int64
+	}{
+		{1024, 1024},
+		{"512", 512},
+		{"10MB", 10e6},
+		{"10 mb", 10e6},
+		{"1.5KiB", 1536},
+		{"2GiB", 2 << 30},
+	}
+	for _, test := range tests {
+		got, err := newValue(test.in).Size()
+		if err != nil {
+			t.Errorf("Size(%v) error: %v", test.in, err)
+		} else if got != test.want {
+			t.Errorf("Size(%v) expected: %v, but got: %v", test.in, test.want, got)
+		}
+	}
+	for _, in := range []string{"MB", "10XB", "1.2.3KB"} {
+		if _, err := newValue(in).Size(); err == nil {
+			t.Errorf("Size(%v) expected an error", in)
+		}
+	}
+}
+
+func TestValueTypedAccessors(t *testing.T) {
+	ts, err := newValue("2023-06-01T12:30:00Z").Time()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2023, 6, 1, 12, 30, 0, 0, time.UTC); !ts.Equal(want) {
+		t.Errorf("expected: %v, but got: %v", want, ts)
+	}
+
+	u, err := newValue("https://example.com:8443/api?x=1").URL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Host != "example.com:8443" || u.Path != "/api" {
+		t.Errorf("unexpected url: %v", u)
+	}
+
+	ss, err := newValue([]interface{}{"a", 1, true}).Strings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ss) != 3 || ss[0] != "a" || ss[1] != "1" || ss[2] != "true" {
+		t.Errorf("unexpected strings: %v", ss)
+	}
+	if ss, _ = newValue("a, b,c").Strings(); len(ss) != 3 || ss[1] != "b" {
+		t.Errorf("unexpected strings: %v", ss)
+	}
+
+	m, err := newValue(map[string]interface{}{"a": 1, "b": "x"}).StringMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["a"] != "1" || m["b"] != "x" {
+		t.Errorf("unexpected map: %v", m)
+	}
+}
+
+func TestValueDefault(t *testing.T) {
+	var v Value = &errValue{err: ErrNotFound}
+	if _, err := v.Int(); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, but got: %v", err)
+	}
+	if d, err := v.Default("5s").Duration(); err != nil || d != 5*time.Second {
+		t.Errorf("expected default 5s, but got: %v %v", d, err)
+	}
+	if n, _ := newValue(8).Default(9).Int(); n != 8 {
+		t.Errorf("expected 8, but got: %v", n)
+	}
+	if n, _ := newValue(nil).Default(9).Int(); n != 9 {
+		t.Errorf("expected 9, but got: %v", n)
+	}
+}
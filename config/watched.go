@@ -0,0 +1,51 @@
+package config
+
+import (
+	"github.com/gotechbook/pkg/logger"
+	"sync/atomic"
+)
+
+// Watched is a typed handle of a config value, it is decoded again
+// whenever the value changes, so Load always returns the latest one.
+type Watched[T any] struct {
+	key   string
+	value atomic.Pointer[T]
+}
+
+// NewWatched returns a Watched decoding the value of key into T.
+func NewWatched[T any](c Config, key string) (*Watched[T], error) {
+	w := &Watched[T]{key: key}
+	if err := w.store(c.Value(key)); err != nil {
+		return nil, err
+	}
+	if err := c.Watch(key, w.observe); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Load returns the latest decoded value, or the zero value of T
+// if the key has been deleted.
+func (w *Watched[T]) Load() T {
+	return *w.value.Load()
+}
+
+func (w *Watched[T]) observe(key string, v Value) {
+	if v.Load() == nil {
+		w.value.Store(new(T))
+		return
+	}
+	// keep the last decoded value if the new one doesn't fit T
+	if err := w.store(v); err != nil {
+		logger.Errorf("failed to decode watched config key: %s error: %v", key, err)
+	}
+}
+
+func (w *Watched[T]) store(v Value) error {
+	t := new(T)
+	if err := v.Scan(t); err != nil {
+		return err
+	}
+	w.value.Store(t)
+	return nil
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	httpstatus "github.com/gotechbook/pkg/status"
 	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 )
 
 const (
@@ -15,7 +18,8 @@ const (
 
 type Error struct {
 	Status
-	cause error
+	cause   error
+	details []proto.Message
 }
 
 func (e *Error) Error() string {
@@ -45,13 +49,33 @@ func (e *Error) WithMetadata(md map[string]string) *Error {
 	return err
 }
 
+// WithDetails returns the error with the gRPC status details, e.g. *errdetails.BadRequest.
+func (e *Error) WithDetails(details ...proto.Message) *Error {
+	err := Clone(e)
+	err.details = append(err.details, details...)
+	return err
+}
+
+// Details returns the gRPC status details other than the error info.
+func (e *Error) Details() []proto.Message {
+	return e.details
+}
+
 func (e *Error) GRPCStatus() *status.Status {
-	s, _ := status.New(httpstatus.ToGRPCCode(int(e.Code)), e.Message).
-		WithDetails(&errdetails.ErrorInfo{
-			Reason:   e.Reason,
-			Metadata: e.Metadata,
-		})
-	return s
+	s := &spb.Status{
+		Code:    int32(httpstatus.ToGRPCCode(int(e.Code))),
+		Message: e.Message,
+	}
+	details := append([]proto.Message{&errdetails.ErrorInfo{
+		Reason:   e.Reason,
+		Metadata: e.Metadata,
+	}}, e.details...)
+	for _, detail := range details {
+		if any, err := anypb.New(detail); err == nil {
+			s.Details = append(s.Details, any)
+		}
+	}
+	return status.FromProto(s)
 }
 
 func New(code int, reason, message string) *Error {
@@ -95,7 +119,8 @@ func Clone(err *Error) *Error {
 		metadata[k] = v
 	}
 	return &Error{
-		cause: err.cause,
+		cause:   err.cause,
+		details: append([]proto.Message(nil), err.details...),
 		Status: Status{
 			Code:     err.Code,
 			Reason:   err.Reason,
@@ -125,7 +150,9 @@ func FromError(err error) *Error {
 		switch d := detail.(type) {
 		case *errdetails.ErrorInfo:
 			ret.Reason = d.Reason
-			return ret.WithMetadata(d.Metadata)
+			ret.Metadata = d.Metadata
+		case proto.Message:
+			ret.details = append(ret.details, d)
 		}
 	}
 	return ret
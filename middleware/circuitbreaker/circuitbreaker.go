@@ -0,0 +1,157 @@
+package circuitbreaker
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware"
+	"github.com/gotechbook/pkg/transport"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNotAllowed is returned when a request is rejected by an open circuit breaker.
+var ErrNotAllowed = errors.ServiceUnavailable("CIRCUITBREAKER", "request failed due to circuit breaker triggered")
+
+// State is the state of a circuit breaker.
+type State int32
+
+const (
+	// StateClosed allows all the requests.
+	StateClosed State = iota
+	// StateOpen rejects the requests, all or some of them.
+	StateOpen
+	// StateHalfOpen allows some requests to probe whether the callee recovered.
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Breaker is a circuit breaker.
+type Breaker interface {
+	// Allow returns an error if the request must be rejected.
+	Allow() error
+	// MarkSuccess marks an allowed request as succeeded.
+	MarkSuccess()
+	// MarkFailed marks an allowed request as failed.
+	MarkFailed()
+	State() State
+}
+
+// KeyFunc returns the key of the request, every key has its own breaker.
+type KeyFunc func(ctx context.Context) string
+
+type Option func(*options)
+
+type options struct {
+	breaker  func() Breaker
+	key      KeyFunc
+	onChange func(key string, from, to State)
+}
+
+// WithBreaker with the constructor of the breakers, NewSRE by default.
+func WithBreaker(f func() Breaker) Option {
+	return func(o *options) {
+		o.breaker = f
+	}
+}
+
+// WithKey with the key of the requests, ByOperation by default.
+func WithKey(k KeyFunc) Option {
+	return func(o *options) {
+		o.key = k
+	}
+}
+
+// WithStateChange with the callback of the state changes of the breakers, e.g. for logging and metrics.
+func WithStateChange(f func(key string, from, to State)) Option {
+	return func(o *options) {
+		o.onChange = f
+	}
+}
+
+// entry is the breaker of a key and its last observed state.
+type entry struct {
+	breaker Breaker
+	state   atomic.Int32
+}
+
+// Client is a client middleware rejecting the requests with ErrNotAllowed when the breaker
+// of their key is open, the requests failing with 5xx errors are marked as failed.
+// The requests canceled by the caller are marked as neither, the callee is not to blame.
+func Client(opts ...Option) middleware.Middleware {
+	o := &options{
+		breaker: func() Breaker { return NewSRE() },
+		key:     ByOperation,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	var (
+		lock    sync.Mutex
+		entries = make(map[string]*entry)
+	)
+	get := func(key string) *entry {
+		lock.Lock()
+		defer lock.Unlock()
+		e, ok := entries[key]
+		if !ok {
+			e = &entry{breaker: o.breaker()}
+			e.state.Store(int32(e.breaker.State()))
+			entries[key] = e
+		}
+		return e
+	}
+	observe := func(key string, e *entry) {
+		to := e.breaker.State()
+		if from := State(e.state.Swap(int32(to))); from != to && o.onChange != nil {
+			o.onChange(key, from, to)
+		}
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			key := o.key(ctx)
+			e := get(key)
+			if err := e.breaker.Allow(); err != nil {
+				observe(key, e)
+				return nil, ErrNotAllowed
+			}
+			observe(key, e)
+			reply, err := handler(ctx, req)
+			switch {
+			case err != nil && errors.Is(ctx.Err(), context.Canceled):
+			case err != nil && errors.Code(err) >= 500:
+				e.breaker.MarkFailed()
+			default:
+				e.breaker.MarkSuccess()
+			}
+			observe(key, e)
+			return reply, err
+		}
+	}
+}
+
+// ByOperation has a breaker for every operation.
+func ByOperation(ctx context.Context) string {
+	if tr, ok := transport.FromClientContext(ctx); ok {
+		return tr.Operation()
+	}
+	return ""
+}
+
+// ByEndpoint has a breaker for every operation of every endpoint.
+func ByEndpoint(ctx context.Context) string {
+	if tr, ok := transport.FromClientContext(ctx); ok {
+		return tr.Endpoint() + tr.Operation()
+	}
+	return ""
+}
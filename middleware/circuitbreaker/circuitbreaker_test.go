@@ -0,0 +1,155 @@
+package circuitbreaker
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware/internal/testutil"
+	"github.com/gotechbook/pkg/transport"
+	"testing"
+	"time"
+)
+
+func TestStateMachine(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewStateMachine(WithFailureThreshold(2), WithOpenTimeout(time.Second))
+	b.now = func() time.Time { return now }
+
+	b.MarkFailed()
+	b.MarkSuccess()
+	b.MarkFailed()
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed after non consecutive failures, but got: %v", b.State())
+	}
+	b.MarkFailed()
+	if b.State() != StateOpen || b.Allow() == nil {
+		t.Fatalf("expected open, but got: %v", b.State())
+	}
+	now = now.Add(time.Second)
+	if b.State() != StateHalfOpen {
+		t.Fatalf("expected half-open, but got: %v", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected a single probe, but got: %v", err)
+	}
+	b.MarkFailed()
+	if b.State() != StateOpen {
+		t.Fatalf("expected open after a failed probe, but got: %v", b.State())
+	}
+	now = now.Add(time.Second)
+	_ = b.Allow()
+	b.MarkSuccess()
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed after a successful probe, but got: %v", b.State())
+	}
+}
+
+func TestStateMachineStuckProbe(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewStateMachine(WithFailureThreshold(1), WithOpenTimeout(time.Second))
+	b.now = func() time.Time { return now }
+	b.MarkFailed()
+	now = now.Add(time.Second)
+	// the probe is never marked
+	if err := b.Allow(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrNotAllowed) {
+		t.Errorf("expected a single probe, but got: %v", err)
+	}
+	now = now.Add(time.Second)
+	if err := b.Allow(); err != nil {
+		t.Errorf("expected a new probe after the timeout, but got: %v", err)
+	}
+	b.MarkSuccess()
+	if b.State() != StateClosed {
+		t.Errorf("expected closed after a successful probe, but got: %v", b.State())
+	}
+}
+
+func TestSREWindow(t *testing.T) {
+	b := NewSRE(WithWindow(time.Second, 0))
+	if len(b.buckets) != 1 || b.bucketDuration != time.Second {
+		t.Errorf("expected 1 bucket of 1s, but got: %d of %v", len(b.buckets), b.bucketDuration)
+	}
+	if err := b.Allow(); err != nil {
+		t.Fatal(err)
+	}
+	b.MarkSuccess()
+}
+
+func TestSRE(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewSRE(WithMinRequests(10))
+	b.now = func() time.Time { return now }
+	for i := 0; i < 10; i++ {
+		b.MarkSuccess()
+	}
+	if b.State() != StateClosed {
+		t.Fatalf("expected closed, but got: %v", b.State())
+	}
+	for i := 0; i < 100; i++ {
+		b.MarkFailed()
+	}
+	if b.State() != StateOpen {
+		t.Fatalf("expected open, but got: %v", b.State())
+	}
+	var rejected int
+	for i := 0; i < 100; i++ {
+		if b.Allow() != nil {
+			rejected++
+		}
+	}
+	if rejected < 50 {
+		t.Errorf("expected most requests to be rejected, but got: %d", rejected)
+	}
+	now = now.Add(3 * time.Second)
+	if b.State() != StateClosed || b.Allow() != nil {
+		t.Errorf("expected closed once the window is over, but got: %v", b.State())
+	}
+}
+
+func TestClient(t *testing.T) {
+	var changes []string
+	m := Client(
+		WithBreaker(func() Breaker { return NewStateMachine(WithFailureThreshold(1)) }),
+		WithStateChange(func(key string, from, to State) {
+			changes = append(changes, key+":"+from.String()+"->"+to.String())
+		}),
+	)
+	call := func(operation string, err error) error {
+		ctx := transport.NewClientContext(context.Background(), testutil.NewTransport(transport.KindGRPC, "127.0.0.1:9000", operation))
+		_, err = m(func(context.Context, interface{}) (interface{}, error) { return nil, err })(ctx, nil)
+		return err
+	}
+	if err := call("/a", errors.NotFound("NOT_FOUND", "")); !errors.IsNotFound(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := call("/a", errors.InternalServer("INTERNAL", "")); !errors.IsInternalServer(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := call("/a", nil)
+	if !errors.IsServiceUnavailable(err) || errors.Reason(err) != "CIRCUITBREAKER" {
+		t.Errorf("expected a CIRCUITBREAKER error, but got: %v", err)
+	}
+	if err = call("/b", nil); err != nil {
+		t.Errorf("expected another operation to be allowed: %v", err)
+	}
+	if len(changes) != 1 || changes[0] != "/a:closed->open" {
+		t.Errorf("unexpected state changes: %v", changes)
+	}
+
+	// the requests canceled by the caller are not failures
+	ctx, cancel := context.WithCancel(transport.NewClientContext(context.Background(), testutil.NewTransport(transport.KindGRPC, "127.0.0.1:9000", "/c")))
+	cancel()
+	for i := 0; i < 2; i++ {
+		if _, err = m(func(ctx context.Context, _ interface{}) (interface{}, error) { return nil, ctx.Err() })(ctx, nil); !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, but got: %v", err)
+		}
+	}
+	if err = call("/c", nil); err != nil {
+		t.Errorf("expected the canceled requests not to open the breaker: %v", err)
+	}
+}
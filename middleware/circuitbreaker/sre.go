@@ -0,0 +1,143 @@
+package circuitbreaker
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+var _ Breaker = (*SRE)(nil)
+
+// SREOption is SRE breaker option.
+type SREOption func(*SRE)
+
+// WithK with the multiplier of the accepted requests, the lower the more aggressive
+// the throttling, 1.5 by default.
+func WithK(k float64) SREOption {
+	return func(b *SRE) {
+		b.k = k
+	}
+}
+
+// WithMinRequests with the number of requests of the window below which nothing is rejected, 100 by default.
+func WithMinRequests(n int64) SREOption {
+	return func(b *SRE) {
+		b.minRequests = n
+	}
+}
+
+// WithWindow with the window of the statistics and its number of buckets, 3s and 30 by default.
+// The buckets are at least 1 and last at least 1ms, so that a window is never empty.
+func WithWindow(d time.Duration, buckets int) SREOption {
+	return func(b *SRE) {
+		if buckets < 1 {
+			buckets = 1
+		}
+		b.bucketDuration = d / time.Duration(buckets)
+		if b.bucketDuration < time.Millisecond {
+			b.bucketDuration = time.Millisecond
+		}
+		b.buckets = make([]bucket, buckets)
+	}
+}
+
+// SRE is the adaptive throttling of the Google SRE book. It rejects the requests locally
+// with the probability max(0, (requests - K * accepts) / (requests + 1)) of the window,
+// it is open while this probability is above 0.
+type SRE struct {
+	k              float64
+	minRequests    int64
+	bucketDuration time.Duration
+	now            func() time.Time
+
+	lock    sync.Mutex
+	buckets []bucket
+	rand    *rand.Rand
+}
+
+// bucket is a bucket of the rolling window, it is reset when its epoch is over.
+type bucket struct {
+	epoch    int64
+	requests int64
+	accepts  int64
+}
+
+// NewSRE returns a SRE breaker.
+func NewSRE(opts ...SREOption) *SRE {
+	b := &SRE{
+		k:              1.5,
+		minRequests:    100,
+		bucketDuration: 100 * time.Millisecond,
+		buckets:        make([]bucket, 30),
+		now:            time.Now,
+		rand:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
+}
+
+func (b *SRE) Allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if p := b.probability(); p > 0 && b.rand.Float64() < p {
+		// the rejected requests count as requests, so that the throttling goes on
+		// until enough requests are accepted again
+		b.add(0)
+		return ErrNotAllowed
+	}
+	return nil
+}
+
+func (b *SRE) MarkSuccess() {
+	b.lock.Lock()
+	b.add(1)
+	b.lock.Unlock()
+}
+
+func (b *SRE) MarkFailed() {
+	b.lock.Lock()
+	b.add(0)
+	b.lock.Unlock()
+}
+
+func (b *SRE) State() State {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.probability() > 0 {
+		return StateOpen
+	}
+	return StateClosed
+}
+
+// probability returns the probability to reject a request.
+func (b *SRE) probability() float64 {
+	epoch := b.epoch()
+	var requests, accepts int64
+	for _, w := range b.buckets {
+		if w.epoch > epoch-int64(len(b.buckets)) {
+			requests += w.requests
+			accepts += w.accepts
+		}
+	}
+	if requests < b.minRequests {
+		return 0
+	}
+	return math.Max(0, (float64(requests)-b.k*float64(accepts))/float64(requests+1))
+}
+
+func (b *SRE) add(accepts int64) {
+	epoch := b.epoch()
+	w := &b.buckets[epoch%int64(len(b.buckets))]
+	if w.epoch != epoch {
+		*w = bucket{epoch: epoch}
+	}
+	w.requests++
+	w.accepts += accepts
+}
+
+func (b *SRE) epoch() int64 {
+	return b.now().UnixNano() / int64(b.bucketDuration)
+}
@@ -0,0 +1,129 @@
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+)
+
+var _ Breaker = (*StateMachine)(nil)
+
+// StateMachineOption is StateMachine breaker option.
+type StateMachineOption func(*StateMachine)
+
+// WithFailureThreshold with the number of consecutive failures opening the breaker, 5 by default.
+func WithFailureThreshold(n int) StateMachineOption {
+	return func(b *StateMachine) {
+		b.failureThreshold = n
+	}
+}
+
+// WithOpenTimeout with the time the breaker stays open before it is half-open, 10s by default.
+func WithOpenTimeout(d time.Duration) StateMachineOption {
+	return func(b *StateMachine) {
+		b.openTimeout = d
+	}
+}
+
+// WithHalfOpenRequests with the number of probe requests allowed while half-open,
+// which must all succeed to close the breaker, 1 by default. The probes never marked,
+// e.g. canceled ones, are allowed again after the open timeout.
+func WithHalfOpenRequests(n int) StateMachineOption {
+	return func(b *StateMachine) {
+		b.halfOpenRequests = n
+	}
+}
+
+// StateMachine is a classic closed, open and half-open circuit breaker.
+type StateMachine struct {
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenRequests int
+	now              func() time.Time
+
+	lock      sync.Mutex
+	state     State
+	failures  int
+	openedAt  time.Time
+	probes    int
+	probedAt  time.Time
+	successes int
+}
+
+// NewStateMachine returns a StateMachine breaker.
+func NewStateMachine(opts ...StateMachineOption) *StateMachine {
+	b := &StateMachine{
+		failureThreshold: 5,
+		openTimeout:      10 * time.Second,
+		halfOpenRequests: 1,
+		now:              time.Now,
+	}
+	for _, o := range opts {
+		o(b)
+	}
+	return b
+}
+
+func (b *StateMachine) Allow() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	switch b.current() {
+	case StateOpen:
+		return ErrNotAllowed
+	case StateHalfOpen:
+		if b.probes >= b.halfOpenRequests {
+			return ErrNotAllowed
+		}
+		b.probes++
+		b.probedAt = b.now()
+	}
+	return nil
+}
+
+func (b *StateMachine) MarkSuccess() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	switch b.current() {
+	case StateClosed:
+		b.failures = 0
+	case StateHalfOpen:
+		if b.successes++; b.successes >= b.halfOpenRequests {
+			b.state, b.failures = StateClosed, 0
+		}
+	}
+}
+
+func (b *StateMachine) MarkFailed() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	switch b.current() {
+	case StateClosed:
+		if b.failures++; b.failures >= b.failureThreshold {
+			b.open()
+		}
+	case StateHalfOpen:
+		b.open()
+	}
+}
+
+func (b *StateMachine) State() State {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.current()
+}
+
+// current returns the state, the open breaker is half-open after the open timeout,
+// and the half-open breaker allows new probes when the last ones are not done within it.
+func (b *StateMachine) current() State {
+	now := b.now()
+	switch {
+	case b.state == StateOpen && now.Sub(b.openedAt) >= b.openTimeout:
+		b.state, b.probes, b.successes = StateHalfOpen, 0, 0
+	case b.state == StateHalfOpen && b.probes >= b.halfOpenRequests && now.Sub(b.probedAt) >= b.openTimeout:
+		b.probes, b.successes = 0, 0
+	}
+	return b.state
+}
+
+func (b *StateMachine) open() {
+	b.state, b.openedAt = StateOpen, b.now()
+}
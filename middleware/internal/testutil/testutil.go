@@ -0,0 +1,50 @@
+// Package testutil provides the transport fixtures of the middleware tests.
+package testutil
+
+import (
+	"github.com/gotechbook/pkg/transport"
+)
+
+var (
+	_ transport.Header      = (Header)(nil)
+	_ transport.Transporter = (*Transport)(nil)
+)
+
+// Header is a header of a map.
+type Header map[string]string
+
+func (h Header) Get(k string) string { return h[k] }
+func (h Header) Set(k, v string)     { h[k] = v }
+func (h Header) Keys() []string {
+	ks := make([]string, 0, len(h))
+	for k := range h {
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+// Transport is a transporter of the request and reply headers.
+type Transport struct {
+	kind      transport.Kind
+	endpoint  string
+	operation string
+	Request   Header
+	Reply     Header
+}
+
+// NewTransport returns a transport of the operation with empty headers.
+func NewTransport(kind transport.Kind, endpoint, operation string) *Transport {
+	return &Transport{
+		kind:      kind,
+		endpoint:  endpoint,
+		operation: operation,
+		Request:   Header{},
+		Reply:     Header{},
+	}
+}
+
+func (t *Transport) Kind() transport.Kind            { return t.kind }
+func (t *Transport) Endpoint() string                { return t.endpoint }
+func (t *Transport) Operation() string               { return t.operation }
+func (t *Transport) RequestHeader() transport.Header { return t.Request }
+func (t *Transport) ReplyHeader() transport.Header   { return t.Reply }
@@ -0,0 +1,215 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/logger"
+	"github.com/gotechbook/pkg/middleware"
+	"github.com/gotechbook/pkg/transport"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	redacted      = "***"
+	requestIDKey  = "X-Request-ID"
+	forwardedKey  = "X-Forwarded-For"
+	truncatedMark = "...(truncated)"
+)
+
+type Option func(*options)
+
+type options struct {
+	logger     logger.Logger
+	sampleRate float64
+	maxPayload int
+	redact     map[string]struct{}
+}
+
+// WithLogger with the logger of the access logs, the global logger by default.
+func WithLogger(l logger.Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithPayloadSampling logs the request and reply payloads of the given
+// fraction of the requests, from 0 (never, the default) to 1 (always).
+func WithPayloadSampling(rate float64) Option {
+	return func(o *options) {
+		o.sampleRate = rate
+	}
+}
+
+// WithMaxPayloadSize truncates the logged payloads to size bytes, 1024 by default.
+func WithMaxPayloadSize(size int) Option {
+	return func(o *options) {
+		o.maxPayload = size
+	}
+}
+
+// WithRedactFields redacts the payload fields with the given names at any depth,
+// e.g. "password" or "token", names are matched case-insensitively.
+func WithRedactFields(fields ...string) Option {
+	return func(o *options) {
+		for _, f := range fields {
+			o.redact[strings.ToLower(f)] = struct{}{}
+		}
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		maxPayload: 1024,
+		redact:     make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Server is a server middleware logging the requests.
+func Server(opts ...Option) middleware.Middleware {
+	return newMiddleware("server", transport.FromServerContext, opts)
+}
+
+// Client is a client middleware logging the requests.
+func Client(opts ...Option) middleware.Middleware {
+	return newMiddleware("client", transport.FromClientContext, opts)
+}
+
+func newMiddleware(kind string, fromContext func(context.Context) (transport.Transporter, bool), opts []Option) middleware.Middleware {
+	o := newOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var (
+				component string
+				operation string
+				requestID string
+				caller    string
+			)
+			if tr, ok := fromContext(ctx); ok {
+				component = tr.Kind().String()
+				operation = tr.Operation()
+				requestID = tr.RequestHeader().Get(requestIDKey)
+				if kind == "server" {
+					caller = serverCaller(ctx, tr)
+				} else {
+					caller = tr.Endpoint()
+				}
+			}
+			start := time.Now()
+			reply, err := handler(ctx, req)
+			kvs := []interface{}{
+				"kind", kind,
+				"component", component,
+				"operation", operation,
+				"caller", caller,
+				"code", errors.Code(err),
+				"reason", errors.Reason(err),
+				"latency", time.Since(start).Seconds(),
+			}
+			if requestID != "" {
+				kvs = append(kvs, "request_id", requestID)
+			}
+			if err != nil {
+				kvs = append(kvs, "error", err.Error())
+			}
+			if o.sampleRate > 0 && rand.Float64() < o.sampleRate { //nolint:gosec
+				kvs = append(kvs, "request", o.payload(req))
+				if err == nil {
+					kvs = append(kvs, "reply", o.payload(reply))
+				}
+			}
+			l := o.logger
+			if l == nil {
+				l = logger.GetLogger()
+			}
+			_ = logger.WithContext(ctx, l).Log(level(err), kvs...)
+			return reply, err
+		}
+	}
+}
+
+// serverCaller returns the address of the peer, or else of the X-Forwarded-For header.
+func serverCaller(ctx context.Context, tr transport.Transporter) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return tr.RequestHeader().Get(forwardedKey)
+}
+
+// level returns the log level of the error, Warn for client errors and Error for server errors.
+func level(err error) logger.Level {
+	switch code := errors.Code(err); {
+	case err == nil:
+		return logger.LevelInfo
+	case code >= 500:
+		return logger.LevelError
+	default:
+		return logger.LevelWarn
+	}
+}
+
+// payload returns the redacted and truncated representation of a request or reply.
+func (o *options) payload(v interface{}) string {
+	var (
+		data []byte
+		err  error
+	)
+	if m, ok := v.(proto.Message); ok {
+		data, err = protojson.Marshal(m)
+	} else {
+		data, err = json.Marshal(v)
+	}
+	s := fmt.Sprintf("%+v", v)
+	if err == nil {
+		s = string(data)
+		if len(o.redact) > 0 {
+			var doc interface{}
+			if json.Unmarshal(data, &doc) == nil {
+				if data, err = json.Marshal(o.redactValue(doc)); err == nil {
+					s = string(data)
+				}
+			}
+		}
+	} else if len(o.redact) > 0 {
+		// the payload can not be inspected, so it is not logged
+		s = redacted
+	}
+	if o.maxPayload > 0 && len(s) > o.maxPayload {
+		// cut on a rune boundary, so that the payload remains valid utf-8
+		n := o.maxPayload
+		for n > 0 && !utf8.RuneStart(s[n]) {
+			n--
+		}
+		s = s[:n] + truncatedMark
+	}
+	return s
+}
+
+func (o *options) redactValue(v interface{}) interface{} {
+	switch vt := v.(type) {
+	case map[string]interface{}:
+		for k, e := range vt {
+			if _, ok := o.redact[strings.ToLower(k)]; ok {
+				vt[k] = redacted
+			} else {
+				vt[k] = o.redactValue(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range vt {
+			vt[i] = o.redactValue(e)
+		}
+	}
+	return v
+}
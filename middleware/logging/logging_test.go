@@ -0,0 +1,88 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/logger"
+	"github.com/gotechbook/pkg/middleware/internal/testutil"
+	"github.com/gotechbook/pkg/transport"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+type testLogger struct {
+	level logger.Level
+	kvs   map[string]interface{}
+}
+
+func (l *testLogger) Log(level logger.Level, keyValue ...interface{}) error {
+	l.level = level
+	l.kvs = make(map[string]interface{})
+	for i := 0; i+1 < len(keyValue); i += 2 {
+		l.kvs[keyValue[i].(string)] = keyValue[i+1]
+	}
+	return nil
+}
+
+func TestServer(t *testing.T) {
+	l := &testLogger{}
+	tr := testutil.NewTransport(transport.KindHTTP, "http://127.0.0.1:8000", "/user.v1.User/Login")
+	tr.Request = testutil.Header{requestIDKey: "rid", forwardedKey: "10.0.0.1"}
+	ctx := transport.NewServerContext(context.Background(), tr)
+	req := map[string]interface{}{"name": "alice", "password": "secret", "bio": strings.Repeat("x", 100)}
+	m := Server(WithLogger(l), WithPayloadSampling(1), WithMaxPayloadSize(64), WithRedactFields("Password"))
+
+	_, err := m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.Unauthorized("TOKEN_EXPIRED", "token expired")
+	})(ctx, req)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if l.level != logger.LevelWarn {
+		t.Errorf("expected level warn, but got: %v", l.level)
+	}
+	want := map[string]interface{}{
+		"kind":       "server",
+		"component":  "http",
+		"operation":  "/user.v1.User/Login",
+		"caller":     "10.0.0.1",
+		"code":       401,
+		"reason":     "TOKEN_EXPIRED",
+		"request_id": "rid",
+	}
+	for k, v := range want {
+		if l.kvs[k] != v {
+			t.Errorf("expected %s: %v, but got: %v", k, v, l.kvs[k])
+		}
+	}
+	payload := l.kvs["request"].(string)
+	if strings.Contains(payload, "secret") || !strings.HasSuffix(payload, truncatedMark) {
+		t.Errorf("expected a redacted and truncated payload, but got: %s", payload)
+	}
+	if _, ok := l.kvs["reply"]; ok {
+		t.Error("expected no reply of a failed request")
+	}
+
+	if _, err = Server(WithLogger(l))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	})(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.kvs["request"]; ok || l.level != logger.LevelInfo || l.kvs["code"] != 200 {
+		t.Errorf("unexpected log: %v %v", l.level, l.kvs)
+	}
+}
+
+func TestPayloadTruncateRune(t *testing.T) {
+	o := newOptions([]Option{WithMaxPayloadSize(4)})
+	// "é" is 2 bytes, the limit falls in the middle of the second one
+	s := o.payload(json.RawMessage(`"éé"`))
+	if s != `"é`+truncatedMark {
+		t.Errorf("unexpected payload: %q", s)
+	}
+	if !utf8.ValidString(s) {
+		t.Errorf("expected a valid utf-8 payload, but got: %q", s)
+	}
+}
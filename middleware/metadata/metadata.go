@@ -0,0 +1,180 @@
+package metadata
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/middleware"
+	"github.com/gotechbook/pkg/transport"
+	"strings"
+)
+
+// DefaultPrefix is the default prefix of the propagated headers.
+const DefaultPrefix = "x-md-global-"
+
+// Metadata is the metadata propagated across the services, its keys are lower case.
+type Metadata map[string]string
+
+// New returns the metadata of the key value pairs.
+func New(kv ...string) Metadata {
+	md := make(Metadata, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		md.Set(kv[i], kv[i+1])
+	}
+	return md
+}
+
+// Get returns the value of the key, the key is case insensitive.
+func (m Metadata) Get(key string) string {
+	return m[strings.ToLower(key)]
+}
+
+// Set sets the value of the key, the key is case insensitive.
+func (m Metadata) Set(key, value string) {
+	if key == "" || value == "" {
+		return
+	}
+	m[strings.ToLower(key)] = value
+}
+
+// Clone returns a copy of the metadata.
+func (m Metadata) Clone() Metadata {
+	md := make(Metadata, len(m))
+	for k, v := range m {
+		md[k] = v
+	}
+	return md
+}
+
+type serverMetadataKey struct{}
+
+type clientMetadataKey struct{}
+
+// NewServerContext returns a new Context that carries the metadata of the server request.
+func NewServerContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, serverMetadataKey{}, md)
+}
+
+// FromServerContext returns the metadata of the server request stored in ctx, if any.
+func FromServerContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(serverMetadataKey{}).(Metadata)
+	return md, ok
+}
+
+// NewClientContext returns a new Context that carries the metadata of the client calls.
+func NewClientContext(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, clientMetadataKey{}, md)
+}
+
+// FromClientContext returns the metadata of the client calls stored in ctx, if any.
+func FromClientContext(ctx context.Context) (Metadata, bool) {
+	md, ok := ctx.Value(clientMetadataKey{}).(Metadata)
+	return md, ok
+}
+
+// AppendToClientContext returns a new Context with the key value pairs added
+// to the metadata of the client calls.
+func AppendToClientContext(ctx context.Context, kv ...string) context.Context {
+	md, _ := FromClientContext(ctx)
+	md = md.Clone()
+	for k, v := range New(kv...) {
+		md[k] = v
+	}
+	return NewClientContext(ctx, md)
+}
+
+type Option func(*options)
+
+type options struct {
+	prefix    []string
+	constants Metadata
+}
+
+// WithPrefix with the prefixes of the propagated headers, DefaultPrefix by default,
+// e.g. WithPrefix(DefaultPrefix, "x-tenant-", "x-canary").
+func WithPrefix(prefix ...string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithConstants with the metadata added to all the client calls, e.g. the name of the caller.
+func WithConstants(md Metadata) Option {
+	return func(o *options) {
+		o.constants = md
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		prefix: []string{DefaultPrefix},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	// lower the prefixes in a copy, the slice of WithPrefix is the caller's
+	prefix := make([]string, len(o.prefix))
+	for i, p := range o.prefix {
+		prefix[i] = strings.ToLower(p)
+	}
+	o.prefix = prefix
+	return o
+}
+
+func (o *options) propagated(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range o.prefix {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Server is a server middleware storing the request headers of the prefixes in the context.
+func Server(opts ...Option) middleware.Middleware {
+	o := newOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				md := Metadata{}
+				header := tr.RequestHeader()
+				for _, k := range header.Keys() {
+					if o.propagated(k) {
+						md.Set(k, header.Get(k))
+					}
+				}
+				ctx = NewServerContext(ctx, md)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+// Client is a client middleware setting the request headers of the constants, of the metadata
+// of the server request of the prefixes and of the metadata of the client calls, the latter
+// overriding the former.
+func Client(opts ...Option) middleware.Middleware {
+	o := newOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				header := tr.RequestHeader()
+				for k, v := range o.constants {
+					header.Set(k, v)
+				}
+				if md, ok := FromServerContext(ctx); ok {
+					for k, v := range md {
+						if o.propagated(k) {
+							header.Set(k, v)
+						}
+					}
+				}
+				if md, ok := FromClientContext(ctx); ok {
+					for k, v := range md {
+						header.Set(k, v)
+					}
+				}
+			}
+			return handler(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package metadata
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/middleware/internal/testutil"
+	"github.com/gotechbook/pkg/transport"
+	"net/http"
+	"testing"
+)
+
+func TestMetadata(t *testing.T) {
+	server := testutil.NewTransport(transport.KindHTTP, "127.0.0.1:8000", "/test")
+	server.Request = testutil.Header{
+		http.CanonicalHeaderKey("x-md-global-tenant"): "acme",
+		"x-canary":      "true",
+		"x-md-local-id": "1",
+		"authorization": "Bearer token",
+	}
+	client := testutil.NewTransport(transport.KindHTTP, "127.0.0.1:8000", "/test")
+	opts := []Option{WithPrefix(DefaultPrefix, "X-Canary"), WithConstants(New("x-md-global-caller", "orders"))}
+
+	_, err := Server(opts...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		md, ok := FromServerContext(ctx)
+		if !ok || len(md) != 2 || md.Get("X-Md-Global-Tenant") != "acme" || md.Get("x-canary") != "true" {
+			t.Errorf("unexpected server metadata: %v", md)
+		}
+		ctx = AppendToClientContext(ctx, "x-md-global-tenant", "override", "x-locale", "fr")
+		ctx = transport.NewClientContext(ctx, client)
+		return Client(opts...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})(ctx, req)
+	})(transport.NewServerContext(context.Background(), server), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testutil.Header{
+		"x-md-global-caller": "orders",
+		"x-md-global-tenant": "override",
+		"x-canary":           "true",
+		"x-locale":           "fr",
+	}
+	if len(client.Request) != len(want) {
+		t.Fatalf("expected headers: %v, but got: %v", want, client.Request)
+	}
+	for k, v := range want {
+		if client.Request[k] != v {
+			t.Errorf("expected %s: %s, but got: %s", k, v, client.Request[k])
+		}
+	}
+}
+
+func TestPrefixNotMutated(t *testing.T) {
+	prefix := []string{"X-Md-Global-", "X-Canary"}
+	_ = Server(WithPrefix(prefix...))
+	if prefix[0] != "X-Md-Global-" || prefix[1] != "X-Canary" {
+		t.Errorf("expected the prefixes not to be mutated, but got: %v", prefix)
+	}
+}
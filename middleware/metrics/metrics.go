@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware"
+	"github.com/gotechbook/pkg/transport"
+	"strconv"
+	"time"
+)
+
+// Counter is a counter metric, With returns the counter of the label values.
+type Counter interface {
+	With(lvs ...string) Counter
+	Inc()
+}
+
+// Observer is a histogram or summary metric, With returns the observer of the label values.
+type Observer interface {
+	With(lvs ...string) Observer
+	Observe(float64)
+}
+
+// Gauge is a gauge metric, With returns the gauge of the label values.
+type Gauge interface {
+	With(lvs ...string) Gauge
+	Add(float64)
+}
+
+type Option func(*options)
+
+type options struct {
+	requests Counter
+	seconds  Observer
+	inFlight Gauge
+}
+
+// WithRequests with the counter of the requests, labeled by kind, operation, code and reason.
+func WithRequests(c Counter) Option {
+	return func(o *options) {
+		o.requests = c
+	}
+}
+
+// WithSeconds with the observer of the request latencies in seconds,
+// labeled by kind, operation, code and reason.
+func WithSeconds(h Observer) Option {
+	return func(o *options) {
+		o.seconds = h
+	}
+}
+
+// WithInFlight with the gauge of the requests in flight, labeled by kind and operation.
+func WithInFlight(g Gauge) Option {
+	return func(o *options) {
+		o.inFlight = g
+	}
+}
+
+// Server is a server middleware recording the request metrics.
+func Server(opts ...Option) middleware.Middleware {
+	return newMiddleware(transport.FromServerContext, opts)
+}
+
+// Client is a client middleware recording the request metrics.
+func Client(opts ...Option) middleware.Middleware {
+	return newMiddleware(transport.FromClientContext, opts)
+}
+
+func newMiddleware(fromContext func(context.Context) (transport.Transporter, bool), opts []Option) middleware.Middleware {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var kind, operation string
+			if tr, ok := fromContext(ctx); ok {
+				kind = tr.Kind().String()
+				operation = tr.Operation()
+			}
+			if o.inFlight != nil {
+				g := o.inFlight.With(kind, operation)
+				g.Add(1)
+				defer g.Add(-1)
+			}
+			start := time.Now()
+			reply, err := handler(ctx, req)
+			code := strconv.Itoa(errors.Code(err))
+			reason := errors.Reason(err)
+			if o.requests != nil {
+				o.requests.With(kind, operation, code, reason).Inc()
+			}
+			if o.seconds != nil {
+				o.seconds.With(kind, operation, code, reason).Observe(time.Since(start).Seconds())
+			}
+			return reply, err
+		}
+	}
+}
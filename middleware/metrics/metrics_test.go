@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware/internal/testutil"
+	"github.com/gotechbook/pkg/transport"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// testMetric records the values by their joined label values.
+type testMetric struct {
+	lock   *sync.Mutex
+	lvs    string
+	values map[string]float64
+}
+
+func newTestMetric() *testMetric {
+	return &testMetric{lock: &sync.Mutex{}, values: make(map[string]float64)}
+}
+
+func (m *testMetric) with(lvs []string) *testMetric {
+	return &testMetric{lock: m.lock, lvs: strings.Join(lvs, ","), values: m.values}
+}
+
+func (m *testMetric) add(v float64) {
+	m.lock.Lock()
+	m.values[m.lvs] += v
+	m.lock.Unlock()
+}
+
+type testCounter struct{ *testMetric }
+
+func (c testCounter) With(lvs ...string) Counter { return testCounter{c.with(lvs)} }
+func (c testCounter) Inc()                       { c.add(1) }
+
+type testGauge struct{ *testMetric }
+
+func (g testGauge) With(lvs ...string) Gauge { return testGauge{g.with(lvs)} }
+func (g testGauge) Add(v float64)            { g.add(v) }
+
+func TestServer(t *testing.T) {
+	requests, inFlight := testCounter{newTestMetric()}, testGauge{newTestMetric()}
+	m := Server(WithRequests(requests), WithInFlight(inFlight))
+	ctx := transport.NewServerContext(context.Background(), testutil.NewTransport(transport.KindGRPC, "", "/user.v1.User/Get"))
+	_, _ = m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if v := inFlight.values["grpc,/user.v1.User/Get"]; v != 1 {
+			t.Errorf("expected 1 request in flight, but got: %v", v)
+		}
+		return nil, errors.NotFound("USER_NOT_FOUND", "user not found")
+	})(ctx, nil)
+	_, _ = m(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})(ctx, nil)
+
+	want := map[string]float64{
+		"grpc,/user.v1.User/Get,404,USER_NOT_FOUND": 1,
+		"grpc,/user.v1.User/Get,200,":               1,
+	}
+	for k, v := range want {
+		if requests.values[k] != v {
+			t.Errorf("expected %s: %v, but got: %v", k, v, requests.values[k])
+		}
+	}
+	if v := inFlight.values["grpc,/user.v1.User/Get"]; v != 0 {
+		t.Errorf("expected no request in flight, but got: %v", v)
+	}
+}
+
+func TestPrometheus(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	opts, err := NewPrometheus(reg, "test", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := transport.NewServerContext(context.Background(), testutil.NewTransport(transport.KindGRPC, "", "/user.v1.User/Get"))
+	_, _ = Server(opts...)(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})(ctx, nil)
+
+	srv := httptest.NewServer(Handler(reg))
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	for _, s := range []string{
+		`test_server_requests_total{code="200",kind="grpc",operation="/user.v1.User/Get",reason=""} 1`,
+		`test_server_requests_seconds_count{code="200",kind="grpc",operation="/user.v1.User/Get",reason=""} 1`,
+		`test_server_requests_in_flight{kind="grpc",operation="/user.v1.User/Get"} 0`,
+	} {
+		if !strings.Contains(string(data), s) {
+			t.Errorf("expected %s in:\n%s", s, data)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	_ Counter  = (*counter)(nil)
+	_ Observer = (*observer)(nil)
+	_ Gauge    = (*gauge)(nil)
+)
+
+// requestLabels and inFlightLabels are the labels of the metrics of the middlewares.
+var (
+	requestLabels  = []string{"kind", "operation", "code", "reason"}
+	inFlightLabels = []string{"kind", "operation"}
+)
+
+type counter struct {
+	cv  *prometheus.CounterVec
+	lvs []string
+}
+
+// NewCounter returns a Counter of the prometheus counter vector.
+func NewCounter(cv *prometheus.CounterVec) Counter {
+	return &counter{cv: cv}
+}
+
+func (c *counter) With(lvs ...string) Counter {
+	return &counter{cv: c.cv, lvs: lvs}
+}
+
+func (c *counter) Inc() {
+	c.cv.WithLabelValues(c.lvs...).Inc()
+}
+
+type observer struct {
+	ov  prometheus.ObserverVec
+	lvs []string
+}
+
+// NewObserver returns an Observer of the prometheus histogram or summary vector.
+func NewObserver(ov prometheus.ObserverVec) Observer {
+	return &observer{ov: ov}
+}
+
+func (o *observer) With(lvs ...string) Observer {
+	return &observer{ov: o.ov, lvs: lvs}
+}
+
+func (o *observer) Observe(v float64) {
+	o.ov.WithLabelValues(o.lvs...).Observe(v)
+}
+
+type gauge struct {
+	gv  *prometheus.GaugeVec
+	lvs []string
+}
+
+// NewGauge returns a Gauge of the prometheus gauge vector.
+func NewGauge(gv *prometheus.GaugeVec) Gauge {
+	return &gauge{gv: gv}
+}
+
+func (g *gauge) With(lvs ...string) Gauge {
+	return &gauge{gv: g.gv, lvs: lvs}
+}
+
+func (g *gauge) Add(v float64) {
+	g.gv.WithLabelValues(g.lvs...).Add(v)
+}
+
+// NewPrometheus registers the request metrics of a side, e.g. "server" or "client",
+// and returns the options recording them:
+//
+//	<namespace>_<side>_requests_total
+//	<namespace>_<side>_requests_seconds
+//	<namespace>_<side>_requests_in_flight
+func NewPrometheus(reg prometheus.Registerer, namespace, side string) ([]Option, error) {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: side,
+		Name:      "requests_total",
+		Help:      "The total number of requests.",
+	}, requestLabels)
+	seconds := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: side,
+		Name:      "requests_seconds",
+		Help:      "The request latencies in seconds.",
+		Buckets:   prometheus.DefBuckets,
+	}, requestLabels)
+	inFlight := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: side,
+		Name:      "requests_in_flight",
+		Help:      "The number of requests in flight.",
+	}, inFlightLabels)
+	for _, c := range []prometheus.Collector{requests, seconds, inFlight} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return []Option{
+		WithRequests(NewCounter(requests)),
+		WithSeconds(NewObserver(seconds)),
+		WithInFlight(NewGauge(inFlight)),
+	}, nil
+}
+
+// Handler returns the handler of the text exposition of the metrics
+// gathered by g, e.g. prometheus.DefaultGatherer, to mount on the admin server.
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var _ Limiter = (*BBR)(nil)
+
+// BBROption is BBR limiter option.
+type BBROption func(*BBR)
+
+// WithWindow with the window of the pass and latency statistics
+// and its number of buckets, 10s and 100 by default. The buckets are at least 1
+// and last at least 1ms, so that a window is never empty.
+func WithWindow(d time.Duration, buckets int) BBROption {
+	return func(l *BBR) {
+		if buckets < 1 {
+			buckets = 1
+		}
+		l.bucketDuration = d / time.Duration(buckets)
+		if l.bucketDuration < time.Millisecond {
+			l.bucketDuration = time.Millisecond
+		}
+		l.buckets = buckets
+	}
+}
+
+// WithCPUThreshold with the cpu usage in per mille from which requests are shed, 800 by default.
+func WithCPUThreshold(threshold int64) BBROption {
+	return func(l *BBR) {
+		l.cpuThreshold = threshold
+	}
+}
+
+// WithCPU with the cpu usage in per mille, by default it is sampled from /proc/stat.
+func WithCPU(cpu func() int64) BBROption {
+	return func(l *BBR) {
+		l.cpu = cpu
+	}
+}
+
+// BBR is an adaptive limiter inspired by TCP BBR. When the cpu usage is over the threshold,
+// or was shortly before, it rejects the requests exceeding the estimated capacity,
+// which is the max passed requests per second times the min latency of the window.
+type BBR struct {
+	cpu            func() int64
+	cpuThreshold   int64
+	buckets        int
+	bucketDuration time.Duration
+	now            func() time.Time
+
+	inFlight int64
+	prevDrop atomic.Int64
+
+	lock sync.Mutex
+	pass []windowBucket
+	rt   []windowBucket
+}
+
+// windowBucket is a bucket of the rolling window, it is reset when its epoch is over.
+type windowBucket struct {
+	epoch int64
+	sum   float64
+	count int64
+}
+
+// NewBBR returns a BBR limiter, all the keys share the limits.
+func NewBBR(opts ...BBROption) *BBR {
+	l := &BBR{
+		cpu:            systemCPU,
+		cpuThreshold:   800,
+		buckets:        100,
+		bucketDuration: 100 * time.Millisecond,
+		now:            time.Now,
+	}
+	for _, o := range opts {
+		o(l)
+	}
+	l.pass = make([]windowBucket, l.buckets)
+	l.rt = make([]windowBucket, l.buckets)
+	return l
+}
+
+func (l *BBR) Allow(context.Context, string) (DoneFunc, error) {
+	if l.shouldDrop() {
+		return nil, ErrLimitExceed
+	}
+	atomic.AddInt64(&l.inFlight, 1)
+	start := l.now()
+	return func() {
+		rt := float64(l.now().Sub(start)) / float64(time.Millisecond)
+		atomic.AddInt64(&l.inFlight, -1)
+		l.lock.Lock()
+		epoch := l.epoch()
+		l.add(l.rt, epoch, rt)
+		l.add(l.pass, epoch, 1)
+		l.lock.Unlock()
+	}, nil
+}
+
+func (l *BBR) shouldDrop() bool {
+	now := l.now().UnixNano()
+	if l.cpu() < l.cpuThreshold {
+		// keep shedding for a second after a drop, as the cpu usage decays slowly
+		prev := l.prevDrop.Load()
+		if prev == 0 || now-prev > int64(time.Second) {
+			return false
+		}
+		return l.overloaded()
+	}
+	if !l.overloaded() {
+		return false
+	}
+	l.prevDrop.Store(now)
+	return true
+}
+
+func (l *BBR) overloaded() bool {
+	inFlight := atomic.LoadInt64(&l.inFlight)
+	return inFlight > 1 && inFlight > l.maxInFlight()
+}
+
+// maxInFlight returns the estimated capacity of requests in flight.
+func (l *BBR) maxInFlight() int64 {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	epoch := l.epoch()
+	maxPass := l.reduce(l.pass, epoch, func(b windowBucket) float64 { return b.sum })
+	if maxPass <= 0 {
+		maxPass = 1
+	}
+	minRT := -l.reduce(l.rt, epoch, func(b windowBucket) float64 {
+		return -math.Ceil(b.sum / float64(b.count))
+	})
+	if minRT <= 0 {
+		minRT = 1
+	}
+	bucketsPerSecond := float64(time.Second) / float64(l.bucketDuration)
+	return int64(math.Floor(maxPass*minRT*bucketsPerSecond/1000 + 0.5))
+}
+
+func (l *BBR) epoch() int64 {
+	return l.now().UnixNano() / int64(l.bucketDuration)
+}
+
+func (l *BBR) add(w []windowBucket, epoch int64, v float64) {
+	b := &w[epoch%int64(len(w))]
+	if b.epoch != epoch {
+		*b = windowBucket{epoch: epoch}
+	}
+	b.sum += v
+	b.count++
+}
+
+// reduce returns the max of f over the completed buckets of the window, or 0.
+func (l *BBR) reduce(w []windowBucket, epoch int64, f func(windowBucket) float64) float64 {
+	result, found := 0.0, false
+	for _, b := range w {
+		if b.count == 0 || b.epoch >= epoch || b.epoch <= epoch-int64(len(w)) {
+			continue
+		}
+		if v := f(b); !found || v > result {
+			result, found = v, true
+		}
+	}
+	return result
+}
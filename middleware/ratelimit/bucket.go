@@ -0,0 +1,75 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+var _ Limiter = (*TokenBucket)(nil)
+
+// maxBuckets is the number of keys from which the full buckets are evicted.
+const maxBuckets = 10000
+
+// TokenBucket is a token bucket limiter, every key has its own bucket.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	lock    sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket returns a limiter allowing rate requests per second
+// and bursts of burst requests for every key.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	return &TokenBucket{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *TokenBucket) Allow(_ context.Context, key string) (DoneFunc, error) {
+	now := l.now()
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= maxBuckets {
+			l.evict(now)
+		}
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+	if b.tokens < 1 {
+		return nil, ErrLimitExceed
+	}
+	b.tokens--
+	return func() {}, nil
+}
+
+func (l *TokenBucket) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(l.burst, b.tokens+elapsed*l.rate)
+		b.last = now
+	}
+}
+
+// evict removes the full buckets, which are the same as new ones.
+func (l *TokenBucket) evict(now time.Time) {
+	for k, b := range l.buckets {
+		if l.refill(b, now); b.tokens >= l.burst {
+			delete(l.buckets, k)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const cpuSampleInterval = 500 * time.Millisecond
+
+var (
+	cpuOnce  sync.Once
+	cpuUsage atomic.Int64
+)
+
+// systemCPU returns the decayed cpu usage of the system in per mille, sampled from /proc/stat.
+// It is 0 where /proc/stat is not available, so the BBR limiter does not shed requests.
+func systemCPU() int64 {
+	cpuOnce.Do(func() {
+		idle, total, err := readCPUStat()
+		if err != nil {
+			return
+		}
+		go func() {
+			ticker := time.NewTicker(cpuSampleInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				i, t, err := readCPUStat()
+				if err != nil || t <= total {
+					continue
+				}
+				usage := 1000 * (1 - float64(i-idle)/float64(t-total))
+				idle, total = i, t
+				// decay the usage, as the samples are noisy
+				prev := cpuUsage.Load()
+				cpuUsage.Store(int64(float64(prev)*0.95 + usage*0.05))
+			}
+		}()
+	})
+	return cpuUsage.Load()
+}
+
+// readCPUStat returns the idle and the total cpu time of the system.
+func readCPUStat() (idle, total uint64, err error) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		for i, field := range fields[1:] {
+			v, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, err
+			}
+			total += v
+			// idle and iowait
+			if i == 3 || i == 4 {
+				idle += v
+			}
+		}
+		return idle, total, nil
+	}
+	return 0, 0, os.ErrNotExist
+}
@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware"
+	"github.com/gotechbook/pkg/oauth2"
+	"github.com/gotechbook/pkg/transport"
+	"google.golang.org/grpc/peer"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ErrLimitExceed is returned when a request is rejected by a limiter.
+var ErrLimitExceed = errors.TooManyRequests("RATELIMIT", "service unavailable due to rate limit exceeded")
+
+// DoneFunc is called when an allowed request is done.
+type DoneFunc func()
+
+// Limiter limits the requests of a key.
+type Limiter interface {
+	// Allow returns ErrLimitExceed if the request must be rejected.
+	Allow(ctx context.Context, key string) (DoneFunc, error)
+}
+
+// KeyFunc returns the key of the request the limits apply to.
+type KeyFunc func(ctx context.Context) string
+
+type Option func(*options)
+
+type options struct {
+	limiter    Limiter
+	operations map[string]Limiter
+	key        KeyFunc
+}
+
+// WithLimiter with the limiter, the adaptive BBR limiter by default.
+func WithLimiter(l Limiter) Option {
+	return func(o *options) {
+		o.limiter = l
+	}
+}
+
+// WithOperation with the limiter of the requests of the operation,
+// the other requests are limited by the limiter of WithLimiter.
+func WithOperation(operation string, l Limiter) Option {
+	return func(o *options) {
+		if o.operations == nil {
+			o.operations = make(map[string]Limiter)
+		}
+		o.operations[operation] = l
+	}
+}
+
+// WithKey with the key of the requests, e.g. ByOperation, ByUser, ByIP or ByForwardedIP,
+// by default all the requests share the limits.
+func WithKey(k KeyFunc) Option {
+	return func(o *options) {
+		o.key = k
+	}
+}
+
+// Server is a server middleware rejecting the requests over the limits with ErrLimitExceed.
+func Server(opts ...Option) middleware.Middleware {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.limiter == nil {
+		o.limiter = NewBBR()
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var key string
+			if o.key != nil {
+				key = o.key(ctx)
+			}
+			limiter := o.limiter
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				if l, ok := o.operations[tr.Operation()]; ok {
+					limiter = l
+				}
+			}
+			done, err := limiter.Allow(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			defer done()
+			return handler(ctx, req)
+		}
+	}
+}
+
+// ByOperation limits the requests of every operation separately.
+func ByOperation(ctx context.Context) string {
+	if tr, ok := transport.FromServerContext(ctx); ok {
+		return tr.Operation()
+	}
+	return ""
+}
+
+// ByUser limits the requests of every user of the oauth2 user token separately,
+// the requests without user are limited by ByIP.
+func ByUser(ctx context.Context) string {
+	if t, err := oauth2.ExtractUserToken(ctx); err == nil {
+		return "user:" + strconv.FormatUint(t.UserID, 10)
+	}
+	return ByIP(ctx)
+}
+
+// ByIP limits the requests of every client ip separately, the ip is the address of the peer.
+// The forwarded headers are ignored, as any client can set them, see ByForwardedIP.
+func ByIP(ctx context.Context) string {
+	return ipKey(peerIP(ctx))
+}
+
+// ByForwardedIP returns a KeyFunc limiting the requests of every client ip separately behind
+// the trusted proxies, which are ips or cidrs such as "10.0.0.0/8". When the peer is a trusted
+// proxy, the ip is the right-most address of the X-Forwarded-For header which is not a trusted
+// proxy, or the X-Real-IP header, otherwise it is the address of the peer.
+func ByForwardedIP(proxies ...string) (KeyFunc, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if ip := net.ParseIP(p); ip != nil {
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit: invalid trusted proxy %q: %w", p, err)
+		}
+		nets = append(nets, n)
+	}
+	trusted := func(ip net.IP) bool {
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(ctx context.Context) string {
+		ip := peerIP(ctx)
+		tr, ok := transport.FromServerContext(ctx)
+		if ip == nil || !trusted(ip) || !ok {
+			return ipKey(ip)
+		}
+		if forwarded := tr.RequestHeader().Get("X-Forwarded-For"); forwarded != "" {
+			addrs := strings.Split(forwarded, ",")
+			for i := len(addrs) - 1; i >= 0; i-- {
+				// a malformed address is not trusted, the last hop is kept
+				addr := net.ParseIP(strings.TrimSpace(addrs[i]))
+				if addr == nil {
+					break
+				}
+				if ip = addr; !trusted(addr) {
+					break
+				}
+			}
+			return ipKey(ip)
+		}
+		if addr := net.ParseIP(tr.RequestHeader().Get("X-Real-IP")); addr != nil {
+			ip = addr
+		}
+		return ipKey(ip)
+	}, nil
+}
+
+// peerIP returns the ip of the peer, if any.
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	return net.ParseIP(addr)
+}
+
+func ipKey(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	return "ip:" + ip.String()
+}
@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware/internal/testutil"
+	"github.com/gotechbook/pkg/oauth2"
+	"github.com/gotechbook/pkg/transport"
+	"google.golang.org/grpc/peer"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := NewTokenBucket(2, 2)
+	l.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if _, err := l.Allow(context.Background(), "a"); err != nil {
+			t.Fatalf("expected the burst to be allowed: %v", err)
+		}
+	}
+	if _, err := l.Allow(context.Background(), "a"); !errors.Is(err, ErrLimitExceed) {
+		t.Errorf("expected ErrLimitExceed, but got: %v", err)
+	}
+	if _, err := l.Allow(context.Background(), "b"); err != nil {
+		t.Errorf("expected another key to be allowed: %v", err)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if _, err := l.Allow(context.Background(), "a"); err != nil {
+		t.Errorf("expected a refilled token to be allowed: %v", err)
+	}
+}
+
+func TestBBR(t *testing.T) {
+	var (
+		now = time.Unix(0, 0)
+		cpu = int64(0)
+	)
+	l := NewBBR(WithCPU(func() int64 { return cpu }), WithWindow(time.Second, 10))
+	l.now = func() time.Time { return now }
+	// 10 requests per bucket of 100ms with a latency of 10ms, a capacity of 1 request in flight
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			done, err := l.Allow(context.Background(), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			now = now.Add(10 * time.Millisecond)
+			done()
+		}
+	}
+	var dones []DoneFunc
+	for i := 0; i < 3; i++ {
+		done, err := l.Allow(context.Background(), "")
+		if err != nil {
+			t.Fatalf("expected no shedding under low cpu: %v", err)
+		}
+		dones = append(dones, done)
+	}
+	cpu = 900
+	if _, err := l.Allow(context.Background(), ""); !errors.Is(err, ErrLimitExceed) {
+		t.Errorf("expected ErrLimitExceed under high cpu, but got: %v", err)
+	}
+	cpu = 0
+	if _, err := l.Allow(context.Background(), ""); !errors.Is(err, ErrLimitExceed) {
+		t.Errorf("expected shedding to continue shortly after a drop, but got: %v", err)
+	}
+	for _, done := range dones {
+		done()
+	}
+	if _, err := l.Allow(context.Background(), ""); err != nil {
+		t.Errorf("expected the requests to be allowed again: %v", err)
+	}
+}
+
+func TestBBRWindow(t *testing.T) {
+	l := NewBBR(WithCPU(func() int64 { return 0 }), WithWindow(time.Second, 0))
+	if l.buckets != 1 || l.bucketDuration != time.Second {
+		t.Errorf("expected 1 bucket of 1s, but got: %d of %v", l.buckets, l.bucketDuration)
+	}
+	done, err := l.Allow(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done()
+	if l = NewBBR(WithWindow(time.Microsecond, 10)); l.bucketDuration != time.Millisecond {
+		t.Errorf("expected buckets of 1ms, but got: %v", l.bucketDuration)
+	}
+}
+
+func TestServer(t *testing.T) {
+	m := Server(WithLimiter(NewTokenBucket(0, 1)), WithKey(ByUser))
+	h := m(func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+	ctx := context.WithValue(context.Background(), oauth2.CtxUserTokenKey, &oauth2.UserToken{UserID: 1})
+	if _, err := h(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := h(ctx, nil)
+	if !errors.IsTooManyRequests(err) || errors.Reason(err) != "RATELIMIT" {
+		t.Fatalf("expected a RATELIMIT error, but got: %v", err)
+	}
+	if s, _ := status.FromError(err); s.Code() != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted, but got: %v", s.Code())
+	}
+	other := context.WithValue(context.Background(), oauth2.CtxUserTokenKey, &oauth2.UserToken{UserID: 2})
+	if _, err = h(other, nil); err != nil {
+		t.Errorf("expected another user to be allowed: %v", err)
+	}
+}
+
+func TestByIP(t *testing.T) {
+	newContext := func(peerAddr string, header testutil.Header) context.Context {
+		tr := testutil.NewTransport(transport.KindGRPC, "", "/test")
+		tr.Request = header
+		ctx := transport.NewServerContext(context.Background(), tr)
+		return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(peerAddr), Port: 5000}})
+	}
+	spoofed := testutil.Header{"X-Forwarded-For": "1.1.1.1", "X-Real-IP": "2.2.2.2"}
+	if key := ByIP(newContext("203.0.113.7", spoofed)); key != "ip:203.0.113.7" {
+		t.Errorf("expected the forwarded headers to be ignored, but got: %s", key)
+	}
+
+	byIP, err := ByForwardedIP("10.0.0.0/8", "192.168.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key := byIP(newContext("203.0.113.7", spoofed)); key != "ip:203.0.113.7" {
+		t.Errorf("expected the headers of an untrusted peer to be ignored, but got: %s", key)
+	}
+	// the client spoofs the left-most address, the trusted proxies append the real one
+	header := testutil.Header{"X-Forwarded-For": "1.1.1.1, 198.51.100.2, 10.0.0.2"}
+	if key := byIP(newContext("192.168.0.1", header)); key != "ip:198.51.100.2" {
+		t.Errorf("expected the right-most untrusted address, but got: %s", key)
+	}
+	if key := byIP(newContext("10.0.0.1", testutil.Header{"X-Real-IP": "198.51.100.3"})); key != "ip:198.51.100.3" {
+		t.Errorf("expected the real ip of a trusted proxy, but got: %s", key)
+	}
+	if _, err = ByForwardedIP("10.0.0.0/33"); err == nil {
+		t.Error("expected an error of the invalid proxy")
+	}
+}
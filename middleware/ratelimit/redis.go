@@ -0,0 +1,282 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gotechbook/pkg/config"
+	dbredis "github.com/gotechbook/pkg/database/redis"
+	"github.com/gotechbook/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"sync/atomic"
+	"time"
+)
+
+var _ Limiter = (*Redis)(nil)
+
+const (
+	// SlidingWindow is the sliding window algorithm, which weights the count
+	// of the previous window by its overlap with the sliding window.
+	SlidingWindow = "sliding_window"
+	// GCRA is the generic cell rate algorithm, which spaces the requests
+	// evenly and allows bursts of Burst requests.
+	GCRA = "gcra"
+)
+
+// The scripts read the time of redis, so that the instances share the windows whatever
+// their clocks, TIME is allowed before writes with the effects replication of redis >= 5.
+
+var slidingWindowScript = redis.NewScript(`
+redis.replicate_commands()
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local cur = math.floor(now / window)
+local state = redis.call('HMGET', KEYS[1], 'window', 'count', 'prev')
+local last = tonumber(state[1]) or cur
+local count = tonumber(state[2]) or 0
+local prev = tonumber(state[3]) or 0
+if last < cur then
+	if last == cur - 1 then
+		prev = count
+	else
+		prev = 0
+	end
+	count = 0
+end
+local weight = 1 - (now % window) / window
+if prev * weight + count >= limit then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'window', cur, 'count', count + 1, 'prev', prev)
+redis.call('PEXPIRE', KEYS[1], 2 * window)
+return 1
+`)
+
+var gcraScript = redis.NewScript(`
+redis.replicate_commands()
+local emission = tonumber(ARGV[1])
+local tolerance = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + tonumber(time[2]) / 1000
+local tat = tonumber(redis.call('GET', KEYS[1]) or '0')
+if tat < now then
+	tat = now
+end
+tat = tat + emission
+if tat - now > tolerance then
+	return 0
+end
+redis.call('SET', KEYS[1], tostring(tat), 'PX', math.ceil(tat - now))
+return 1
+`)
+
+// Rule is the rule of the requests of an operation, the rule without
+// operation applies to the operations without rule.
+type Rule struct {
+	Operation string
+	// Algorithm is SlidingWindow or GCRA, SlidingWindow by default.
+	Algorithm string
+	// Limit is the number of requests allowed per window.
+	Limit  int
+	Window time.Duration
+	// Burst is the number of requests GCRA allows at once, Limit by default.
+	Burst int
+}
+
+// UnmarshalJSON accepts the window as a duration string such as "1m" or as nanoseconds.
+func (r *Rule) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Operation string          `json:"operation"`
+		Algorithm string          `json:"algorithm"`
+		Limit     int             `json:"limit"`
+		Window    json.RawMessage `json:"window"`
+		Burst     int             `json:"burst"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = Rule{Operation: v.Operation, Algorithm: v.Algorithm, Limit: v.Limit, Burst: v.Burst}
+	if len(v.Window) == 0 {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(v.Window, &s); err != nil {
+		return json.Unmarshal(v.Window, &r.Window)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("ratelimit: invalid window of %q: %w", r.Operation, err)
+	}
+	r.Window = d
+	return nil
+}
+
+func (r Rule) validate() error {
+	switch r.Algorithm {
+	case "", SlidingWindow, GCRA:
+	default:
+		return fmt.Errorf("ratelimit: unknown algorithm %q of %q", r.Algorithm, r.Operation)
+	}
+	// the windows are counted in milliseconds
+	if r.Limit <= 0 || r.Window < time.Millisecond {
+		return fmt.Errorf("ratelimit: invalid limit %d per %v of %q", r.Limit, r.Window, r.Operation)
+	}
+	return nil
+}
+
+// LoadRules loads the rules under the key of the config, e.g.
+//
+//	ratelimit:
+//	  rules:
+//	    - operation: /api.v1.User/Login
+//	      algorithm: gcra
+//	      limit: 10
+//	      window: 1m
+//	      burst: 3
+func LoadRules(c config.Config, key string) ([]Rule, error) {
+	var rules []Rule
+	if err := c.Value(key).Scan(&rules); err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// WithRules with a Redis limiter for every rule, see WithOperation.
+// It returns an error if a rule is invalid.
+func WithRules(client *dbredis.Client, rules []Rule, opts ...RedisOption) (Option, error) {
+	limiters := make([]*Redis, 0, len(rules))
+	for _, r := range rules {
+		l, err := NewRedis(client, r, opts...)
+		if err != nil {
+			return nil, err
+		}
+		limiters = append(limiters, l)
+	}
+	return func(o *options) {
+		for _, l := range limiters {
+			if l.rule.Operation == "" {
+				o.limiter = l
+				continue
+			}
+			if o.operations == nil {
+				o.operations = make(map[string]Limiter)
+			}
+			o.operations[l.rule.Operation] = l
+		}
+	}, nil
+}
+
+type RedisOption func(*Redis)
+
+// WithPrefix with the prefix of the redis keys, "ratelimit:" by default.
+func WithPrefix(prefix string) RedisOption {
+	return func(l *Redis) {
+		l.prefix = prefix
+	}
+}
+
+// WithFallback with the limiter used when redis is unreachable,
+// a token bucket with the rate and the burst of the rule by default.
+func WithFallback(fallback Limiter) RedisOption {
+	return func(l *Redis) {
+		l.fallback = fallback
+	}
+}
+
+// WithTimeout with the timeout of the redis calls, 100ms by default.
+func WithTimeout(timeout time.Duration) RedisOption {
+	return func(l *Redis) {
+		l.timeout = timeout
+	}
+}
+
+// Redis is a distributed limiter sharing the limits of a rule
+// between the instances through redis.
+type Redis struct {
+	client   redis.Scripter
+	rule     Rule
+	prefix   string
+	fallback Limiter
+	timeout  time.Duration
+	// now is the clock of the default fallback, the scripts use the clock of redis
+	now func() time.Time
+	// whether redis is unreachable and the fallback is in use
+	degraded atomic.Bool
+}
+
+// NewRedis returns a Redis limiter of the rule, it returns an error if the rule is invalid.
+func NewRedis(client *dbredis.Client, rule Rule, opts ...RedisOption) (*Redis, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+	l := &Redis{
+		client:  client.Db,
+		rule:    rule,
+		prefix:  "ratelimit:",
+		timeout: 100 * time.Millisecond,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.fallback == nil {
+		burst := rule.Burst
+		if burst <= 0 {
+			burst = rule.Limit
+		}
+		fallback := NewTokenBucket(float64(rule.Limit)/rule.Window.Seconds(), burst)
+		fallback.now = func() time.Time { return l.now() }
+		l.fallback = fallback
+	}
+	return l, nil
+}
+
+func (l *Redis) Allow(ctx context.Context, key string) (DoneFunc, error) {
+	rctx, cancel := context.WithTimeout(ctx, l.timeout)
+	defer cancel()
+	// the keys of a rule share a hash slot, so that the scripts run on redis cluster
+	key = l.prefix + "{" + l.rule.Operation + ":" + key + "}"
+	var (
+		ok  int64
+		err error
+	)
+	if l.rule.Algorithm == GCRA {
+		ok, err = l.gcra(rctx, key)
+	} else {
+		ok, err = l.slidingWindow(rctx, key)
+	}
+	if err != nil {
+		// log the switches only, not every request of an outage
+		if l.degraded.CompareAndSwap(false, true) {
+			logger.Warnw("msg", "ratelimit: redis is unreachable, fallback to the local limiter", "operation", l.rule.Operation, "error", err)
+		}
+		return l.fallback.Allow(ctx, key)
+	}
+	if l.degraded.CompareAndSwap(true, false) {
+		logger.Infow("msg", "ratelimit: redis is reachable, the local limiter is no longer used", "operation", l.rule.Operation)
+	}
+	if ok == 0 {
+		return nil, ErrLimitExceed
+	}
+	return func() {}, nil
+}
+
+func (l *Redis) slidingWindow(ctx context.Context, key string) (int64, error) {
+	return slidingWindowScript.Run(ctx, l.client, []string{key}, l.rule.Limit, l.rule.Window.Milliseconds()).Int64()
+}
+
+func (l *Redis) gcra(ctx context.Context, key string) (int64, error) {
+	burst := l.rule.Burst
+	if burst <= 0 {
+		burst = l.rule.Limit
+	}
+	emission := float64(l.rule.Window.Milliseconds()) / float64(l.rule.Limit)
+	return gcraScript.Run(ctx, l.client, []string{key}, emission, emission*float64(burst)).Int64()
+}
@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gotechbook/pkg/config"
+	"github.com/gotechbook/pkg/config/file"
+	dbredis "github.com/gotechbook/pkg/database/redis"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/redis/go-redis/v9"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := &dbredis.Client{Db: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+	defer client.Db.Close()
+
+	now := time.Unix(1000, 0)
+	// the scripts read the clock of redis, the local clock is only used by the fallback
+	allow := func(l *Redis, key string) error {
+		mr.SetTime(now)
+		l.now = func() time.Time { return now }
+		_, err := l.Allow(context.Background(), key)
+		return err
+	}
+	newRedis := func(rule Rule, opts ...RedisOption) *Redis {
+		l, err := NewRedis(client, rule, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return l
+	}
+
+	sw := newRedis(Rule{Limit: 2, Window: time.Second})
+	for i := 0; i < 2; i++ {
+		if err := allow(sw, "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := allow(sw, "a"); !errors.Is(err, ErrLimitExceed) {
+		t.Errorf("expected ErrLimitExceed, but got: %v", err)
+	}
+	// half of the previous window still counts
+	now = now.Add(1500 * time.Millisecond)
+	if err := allow(sw, "a"); err != nil {
+		t.Errorf("expected to be allowed: %v", err)
+	}
+	if err := allow(sw, "a"); !errors.Is(err, ErrLimitExceed) {
+		t.Errorf("expected ErrLimitExceed, but got: %v", err)
+	}
+
+	// an instance whose clock is skewed shares the window
+	skewed := newRedis(Rule{Operation: "skewed", Limit: 2, Window: time.Second})
+	skewed.now = func() time.Time { return now.Add(700 * time.Millisecond) }
+	mr.SetTime(now)
+	if _, err := skewed.Allow(context.Background(), "a"); err != nil {
+		t.Fatal(err)
+	}
+	other := newRedis(Rule{Operation: "skewed", Limit: 2, Window: time.Second})
+	if err := allow(other, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := skewed.Allow(context.Background(), "a"); !errors.Is(err, ErrLimitExceed) {
+		t.Errorf("expected the skewed instance to share the limit, but got: %v", err)
+	}
+
+	gcra := newRedis(Rule{Algorithm: GCRA, Limit: 10, Window: time.Second, Burst: 2})
+	for i := 0; i < 2; i++ {
+		if err := allow(gcra, "a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := allow(gcra, "a"); !errors.Is(err, ErrLimitExceed) {
+		t.Errorf("expected ErrLimitExceed, but got: %v", err)
+	}
+	now = now.Add(100 * time.Millisecond)
+	if err := allow(gcra, "a"); err != nil {
+		t.Errorf("expected to be allowed after the emission interval: %v", err)
+	}
+
+	// fallback to the local limiter
+	mr.Close()
+	fb := newRedis(Rule{Limit: 1, Window: time.Second}, WithTimeout(10*time.Millisecond))
+	if err := allow(fb, "a"); err != nil {
+		t.Errorf("expected the fallback to allow: %v", err)
+	}
+	if err := allow(fb, "a"); !errors.Is(err, ErrLimitExceed) {
+		t.Errorf("expected the fallback to limit, but got: %v", err)
+	}
+	if !fb.degraded.Load() {
+		t.Error("expected the limiter to be degraded")
+	}
+
+	for _, rule := range []Rule{
+		{Limit: 1},
+		{Limit: 1, Window: time.Microsecond},
+		{Limit: 0, Window: time.Second},
+		{Algorithm: "leaky_bucket", Limit: 1, Window: time.Second},
+	} {
+		if _, err := NewRedis(client, rule); err == nil {
+			t.Errorf("expected an error of the invalid rule %+v", rule)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := `
+ratelimit:
+  rules:
+    - limit: 100
+      window: 1s
+    - operation: /api.v1.User/Login
+      algorithm: gcra
+      limit: 10
+      window: 1m
+      burst: 3
+`
+	if err := os.WriteFile(path, []byte(data), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	c := config.New(config.WithSource(file.NewSource(path)))
+	if err := c.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	rules, err := LoadRules(c, "ratelimit.rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Window != time.Second || rules[1].Algorithm != GCRA || rules[1].Window != time.Minute || rules[1].Burst != 3 {
+		t.Fatalf("unexpected rules: %+v", rules)
+	}
+
+	client := &dbredis.Client{Db: redis.NewClient(&redis.Options{})}
+	opt, err := WithRules(client, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := &options{}
+	opt(o)
+	if o.limiter == nil || o.operations["/api.v1.User/Login"] == nil {
+		t.Errorf("unexpected limiters: %+v", o)
+	}
+	if _, err = WithRules(client, []Rule{{Limit: 1}}); err == nil {
+		t.Error("expected an error of the rule without window")
+	}
+}
@@ -0,0 +1,123 @@
+package requestid
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/gotechbook/pkg/logger"
+	"github.com/gotechbook/pkg/middleware"
+	"github.com/gotechbook/pkg/transport"
+)
+
+// HeaderKey is the default header of the request id.
+const HeaderKey = "X-Request-ID"
+
+// maxLength is the max length of an incoming request id.
+const maxLength = 128
+
+type requestIDKey struct{}
+
+type Option func(*options)
+
+type options struct {
+	header    string
+	generator func() string
+}
+
+// WithHeader with the header of the request id, X-Request-ID by default.
+func WithHeader(key string) Option {
+	return func(o *options) {
+		o.header = key
+	}
+}
+
+// WithGenerator with the generator of the missing request ids, uuid by default.
+func WithGenerator(g func() string) Option {
+	return func(o *options) {
+		o.generator = g
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		header:    HeaderKey,
+		generator: uuid.NewString,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Server is a server middleware reading the request id from the request header,
+// or generating it when missing or invalid. The id is written to the reply header and stored in the context.
+// A valid id has at most 128 characters of [A-Za-z0-9._-], as it is echoed in the headers and logs.
+func Server(opts ...Option) middleware.Middleware {
+	o := newOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromServerContext(ctx); ok {
+				id := tr.RequestHeader().Get(o.header)
+				if !valid(id) {
+					id = o.generator()
+				}
+				tr.ReplyHeader().Set(o.header, id)
+				ctx = NewContext(ctx, id)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+// Client is a client middleware propagating the request id of the context
+// in the request header, an id is generated for the calls without one.
+func Client(opts ...Option) middleware.Middleware {
+	o := newOptions(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				id, ok := FromContext(ctx)
+				if !ok {
+					id = o.generator()
+					ctx = NewContext(ctx, id)
+				}
+				tr.RequestHeader().Set(o.header, id)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+// valid reports whether the incoming id is safe to echo.
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a new Context that carries the request id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// FromContext returns the request id stored in ctx, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// Valuer returns a logger.Valuer of the request id, e.g.
+// logger.With(l, "request_id", requestid.Valuer()) used with logger.WithContext.
+func Valuer() logger.Valuer {
+	return func(ctx context.Context) interface{} {
+		id, _ := FromContext(ctx)
+		return id
+	}
+}
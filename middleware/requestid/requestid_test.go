@@ -0,0 +1,55 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"github.com/gotechbook/pkg/logger"
+	"github.com/gotechbook/pkg/middleware/internal/testutil"
+	"github.com/gotechbook/pkg/transport"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		l      = logger.With(logger.NewStdLogger(&buf), "request_id", Valuer())
+		server = testutil.NewTransport(transport.KindGRPC, "", "/test")
+		client = testutil.NewTransport(transport.KindGRPC, "", "/test")
+	)
+	h := Server(WithGenerator(func() string { return "generated" }))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		_ = logger.WithContext(ctx, l).Log(logger.LevelInfo, "msg", "handled")
+		return Client()(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})(transport.NewClientContext(ctx, client), req)
+	})
+	if _, err := h(transport.NewServerContext(context.Background(), server), nil); err != nil {
+		t.Fatal(err)
+	}
+	if id := server.Reply[HeaderKey]; id != "generated" {
+		t.Errorf("expected the reply header to be generated, but got: %q", id)
+	}
+	if id := client.Request[HeaderKey]; id != "generated" {
+		t.Errorf("expected the id to be propagated, but got: %q", id)
+	}
+	if !strings.Contains(buf.String(), "request_id=generated") {
+		t.Errorf("expected the id in the log, but got: %s", buf.String())
+	}
+
+	server.Request[HeaderKey] = "incoming"
+	if _, err := h(transport.NewServerContext(context.Background(), server), nil); err != nil {
+		t.Fatal(err)
+	}
+	if id := client.Request[HeaderKey]; id != "incoming" {
+		t.Errorf("expected the incoming id to be propagated, but got: %q", id)
+	}
+	for _, id := range []string{"bad id\nlevel=error", strings.Repeat("a", 129)} {
+		server.Request[HeaderKey] = id
+		if _, err := h(transport.NewServerContext(context.Background(), server), nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := server.Reply[HeaderKey]; got != "generated" {
+			t.Errorf("expected the invalid id %q to be replaced, but got: %q", id, got)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// Budget limits the retries to a ratio of the calls of a window, so that the retries
+// of an overloaded callee do not make it worse. It allows a minimum of retries per
+// second for the services with few calls.
+type Budget struct {
+	ratio          float64
+	minPerSecond   int
+	bucketDuration time.Duration
+	now            func() time.Time
+
+	lock    sync.Mutex
+	buckets []budgetBucket
+}
+
+// budgetBucket is a bucket of the rolling window, it is reset when its epoch is over.
+type budgetBucket struct {
+	epoch    int64
+	requests int64
+	retries  int64
+}
+
+// NewBudget returns a budget allowing the retries of ratio of the calls of the last 10s,
+// and at least minPerSecond retries per second, e.g. NewBudget(0.1, 10).
+func NewBudget(ratio float64, minPerSecond int) *Budget {
+	return &Budget{
+		ratio:          ratio,
+		minPerSecond:   minPerSecond,
+		bucketDuration: time.Second,
+		buckets:        make([]budgetBucket, 10),
+		now:            time.Now,
+	}
+}
+
+func (b *Budget) request() {
+	b.lock.Lock()
+	b.bucket().requests++
+	b.lock.Unlock()
+}
+
+// withdraw withdraws a retry if the budget allows it.
+func (b *Budget) withdraw() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	current := b.bucket()
+	var requests, retries int64
+	for _, w := range b.buckets {
+		if w.epoch > current.epoch-int64(len(b.buckets)) {
+			requests += w.requests
+			retries += w.retries
+		}
+	}
+	allowed := int64(b.ratio * float64(requests))
+	if min := int64(b.minPerSecond * len(b.buckets)); allowed < min {
+		allowed = min
+	}
+	if retries >= allowed {
+		return false
+	}
+	current.retries++
+	return true
+}
+
+// bucket returns the current bucket.
+func (b *Budget) bucket() *budgetBucket {
+	epoch := b.now().UnixNano() / int64(b.bucketDuration)
+	w := &b.buckets[epoch%int64(len(b.buckets))]
+	if w.epoch != epoch {
+		*w = budgetBucket{epoch: epoch}
+	}
+	return w
+}
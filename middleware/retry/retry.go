@@ -0,0 +1,231 @@
+package retry
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware"
+	"github.com/gotechbook/pkg/transport"
+	"math/rand"
+	"time"
+)
+
+// Retryable reports whether a failed request can be retried.
+type Retryable func(err error) bool
+
+type Option func(*options)
+
+type options struct {
+	attempts   int
+	base       time.Duration
+	max        time.Duration
+	retryable  Retryable
+	timeout    time.Duration
+	budget     *Budget
+	hedging    time.Duration
+	idempotent func(operation string) bool
+}
+
+// WithAttempts with the max number of attempts of a call including the first one, 3 by default.
+func WithAttempts(n int) Option {
+	return func(o *options) {
+		o.attempts = n
+	}
+}
+
+// WithBackoff with the base and the max of the exponential backoff between the attempts,
+// 100ms and 1s by default, the backoff is jittered between 0 and base * 2^retries.
+func WithBackoff(base, max time.Duration) Option {
+	return func(o *options) {
+		o.base = base
+		o.max = max
+	}
+}
+
+// WithRetryable with the predicate of the retryable errors, ByCode(503, 504) by default.
+func WithRetryable(r Retryable) Option {
+	return func(o *options) {
+		o.retryable = r
+	}
+}
+
+// WithAttemptTimeout with the timeout of every attempt, which never exceeds
+// the deadline of the call, none by default.
+func WithAttemptTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithBudget with the budget shared by the calls to prevent retry storms.
+func WithBudget(b *Budget) Option {
+	return func(o *options) {
+		o.budget = b
+	}
+}
+
+// WithHedging hedges the calls of the idempotent operations: when an attempt has not replied
+// within the delay, another attempt is sent concurrently and the first reply wins.
+// The operations are all the operations if none is given. The attempts run concurrently,
+// so the transport must decode every attempt into its own reply, as the grpc client does.
+func WithHedging(delay time.Duration, operations ...string) Option {
+	return func(o *options) {
+		o.hedging = delay
+		o.idempotent = func(string) bool { return true }
+		if len(operations) > 0 {
+			set := make(map[string]struct{}, len(operations))
+			for _, op := range operations {
+				set[op] = struct{}{}
+			}
+			o.idempotent = func(operation string) bool {
+				_, ok := set[operation]
+				return ok
+			}
+		}
+	}
+}
+
+// ByCode retries the errors of the codes.
+func ByCode(codes ...int) Retryable {
+	return func(err error) bool {
+		code := errors.Code(err)
+		for _, c := range codes {
+			if code == c {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ByReason retries the errors of the reasons.
+func ByReason(reasons ...string) Retryable {
+	return func(err error) bool {
+		reason := errors.Reason(err)
+		for _, r := range reasons {
+			if reason == r {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Client is a client middleware retrying the failed calls with a jittered exponential backoff.
+// It should be the last client middleware, so that the others run once per call.
+func Client(opts ...Option) middleware.Middleware {
+	o := &options{
+		attempts:  3,
+		base:      100 * time.Millisecond,
+		max:       time.Second,
+		retryable: ByCode(503, 504),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if o.budget != nil {
+				o.budget.request()
+			}
+			if o.hedging > 0 {
+				if tr, ok := transport.FromClientContext(ctx); ok && o.idempotent(tr.Operation()) {
+					return o.hedge(ctx, handler, req)
+				}
+			}
+			var (
+				reply interface{}
+				err   error
+			)
+			for attempt := 0; ; attempt++ {
+				if reply, err = o.attempt(ctx, handler, req); err == nil || !o.retry(ctx, attempt, err) {
+					return reply, err
+				}
+				select {
+				case <-time.After(o.backoff(attempt)):
+				case <-ctx.Done():
+					return nil, err
+				}
+			}
+		}
+	}
+}
+
+// attempt calls the handler under the attempt timeout.
+func (o *options) attempt(ctx context.Context, handler middleware.Handler, req interface{}) (interface{}, error) {
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	return handler(ctx, req)
+}
+
+// retry reports whether the failed attempt can be retried, withdrawing the retry from the budget.
+func (o *options) retry(ctx context.Context, attempt int, err error) bool {
+	if attempt+1 >= o.attempts || ctx.Err() != nil || !o.retryable(err) {
+		return false
+	}
+	return o.budget == nil || o.budget.withdraw()
+}
+
+// backoff returns the full jittered backoff after the attempt.
+func (o *options) backoff(attempt int) time.Duration {
+	d := o.base << attempt
+	if d <= 0 || d > o.max {
+		d = o.max
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
+
+type result struct {
+	reply interface{}
+	err   error
+}
+
+// hedge sends an attempt, then another one every hedging delay or as soon as an attempt
+// fails with a retryable error, and returns the first successful or final reply.
+func (o *options) hedge(ctx context.Context, handler middleware.Handler, req interface{}) (interface{}, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan result, o.attempts)
+	send := func() {
+		go func() {
+			reply, err := o.attempt(ctx, handler, req)
+			results <- result{reply, err}
+		}()
+	}
+	send()
+	var (
+		sent, done = 1, 0
+		last       error
+	)
+	timer := time.NewTimer(o.hedging)
+	defer timer.Stop()
+	for {
+		select {
+		case r := <-results:
+			done++
+			if r.err == nil || !o.retryable(r.err) {
+				return r.reply, r.err
+			}
+			last = r.err
+			if sent < o.attempts && o.retry(ctx, sent-1, r.err) {
+				sent++
+				send()
+			} else if done == sent {
+				return nil, last
+			}
+		case <-timer.C:
+			if sent < o.attempts && (o.budget == nil || o.budget.withdraw()) {
+				sent++
+				send()
+				timer.Reset(o.hedging)
+			}
+		case <-ctx.Done():
+			if last == nil {
+				last = ctx.Err()
+			}
+			return nil, last
+		}
+	}
+}
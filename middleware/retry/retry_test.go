@@ -0,0 +1,102 @@
+package retry
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware/internal/testutil"
+	"github.com/gotechbook/pkg/transport"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	unavailable := errors.ServiceUnavailable("UNAVAILABLE", "")
+	h := Client(WithBackoff(time.Millisecond, 5*time.Millisecond))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return nil, unavailable
+		}
+		return "ok", nil
+	})
+	if reply, err := h(context.Background(), nil); err != nil || reply != "ok" || calls != 3 {
+		t.Fatalf("expected success at the third attempt, but got: %v %v %d", reply, err, calls)
+	}
+
+	calls = 0
+	h = Client(WithBackoff(time.Millisecond, time.Millisecond))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.BadRequest("BAD", "")
+	})
+	if _, err := h(context.Background(), nil); !errors.IsBadRequest(err) || calls != 1 {
+		t.Errorf("expected no retry of a bad request, but got: %v %d", err, calls)
+	}
+
+	calls = 0
+	h = Client(WithRetryable(ByReason("UNAVAILABLE")), WithBackoff(time.Millisecond, time.Millisecond))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, unavailable
+	})
+	if _, err := h(context.Background(), nil); !errors.IsServiceUnavailable(err) || calls != 3 {
+		t.Errorf("expected 3 attempts, but got: %v %d", err, calls)
+	}
+}
+
+func TestAttemptTimeout(t *testing.T) {
+	var deadlines []time.Duration
+	h := Client(WithAttemptTimeout(time.Second), WithAttempts(2), WithBackoff(time.Millisecond, time.Millisecond))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		deadline, _ := ctx.Deadline()
+		deadlines = append(deadlines, time.Until(deadline))
+		return nil, errors.GatewayTimeout("TIMEOUT", "")
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := h(ctx, nil); !errors.IsGatewayTimeout(err) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deadlines) != 2 || deadlines[0] > time.Second || deadlines[1] > time.Second {
+		t.Errorf("unexpected attempt deadlines: %v", deadlines)
+	}
+}
+
+func TestBudget(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := NewBudget(0.5, 0)
+	b.now = func() time.Time { return now }
+	var calls int32
+	h := Client(WithBudget(b), WithAttempts(10), WithBackoff(0, 0))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return nil, errors.ServiceUnavailable("UNAVAILABLE", "")
+	})
+	for i := 0; i < 4; i++ {
+		_, _ = h(context.Background(), nil)
+	}
+	// 4 calls allow 2 retries
+	if calls != 6 {
+		t.Errorf("expected 6 attempts, but got: %d", calls)
+	}
+}
+
+func TestHedging(t *testing.T) {
+	var calls int32
+	h := Client(WithHedging(10*time.Millisecond, "/test.Idempotent"))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return "hedged", nil
+	})
+	ctx := transport.NewClientContext(context.Background(), testutil.NewTransport(transport.KindGRPC, "127.0.0.1:9000", "/test.Idempotent"))
+	reply, err := h(ctx, nil)
+	if err != nil || reply != "hedged" {
+		t.Fatalf("expected the hedged reply, but got: %v %v", reply, err)
+	}
+
+	calls = 0
+	ctx = transport.NewClientContext(context.Background(), testutil.NewTransport(transport.KindGRPC, "127.0.0.1:9000", "/test.Create"))
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err = h(ctx, nil); err == nil || calls != 1 {
+		t.Errorf("expected no hedging of a non idempotent operation, but got: %v %d", err, calls)
+	}
+}
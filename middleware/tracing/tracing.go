@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/logger"
+	"github.com/gotechbook/pkg/middleware"
+	"github.com/gotechbook/pkg/transport"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/gotechbook/pkg/middleware/tracing"
+
+type Option func(*options)
+
+type options struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracerProvider with the tracer provider, the global provider by default.
+// A provider of the otel sdk with the tracetest.InMemoryExporter records the spans in tests.
+func WithTracerProvider(p trace.TracerProvider) Option {
+	return func(o *options) {
+		o.provider = p
+	}
+}
+
+// WithPropagator with the propagator of the span context,
+// the W3C traceparent/tracestate and baggage headers by default.
+func WithPropagator(p propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = p
+	}
+}
+
+func newTracer(opts []Option) (trace.Tracer, propagation.TextMapPropagator) {
+	o := &options{
+		propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.provider == nil {
+		o.provider = otel.GetTracerProvider()
+	}
+	return o.provider.Tracer(tracerName), o.propagator
+}
+
+// Server is a server middleware starting a span named after the operation,
+// which is the child of the span context extracted from the request header.
+func Server(opts ...Option) middleware.Middleware {
+	tracer, propagator := newTracer(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			ctx = propagator.Extract(ctx, tr.RequestHeader())
+			ctx, span := tracer.Start(ctx, tr.Operation(),
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(attributes(tr)...),
+			)
+			defer span.End()
+			reply, err := handler(ctx, req)
+			setStatus(span, err, true)
+			return reply, err
+		}
+	}
+}
+
+// Client is a client middleware starting a span named after the operation,
+// its span context is injected in the request header.
+func Client(opts ...Option) middleware.Middleware {
+	tracer, propagator := newTracer(opts)
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromClientContext(ctx)
+			if !ok {
+				return handler(ctx, req)
+			}
+			ctx, span := tracer.Start(ctx, tr.Operation(),
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(attributes(tr)...),
+			)
+			defer span.End()
+			propagator.Inject(ctx, tr.RequestHeader())
+			reply, err := handler(ctx, req)
+			setStatus(span, err, false)
+			return reply, err
+		}
+	}
+}
+
+func attributes(tr transport.Transporter) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("transport.kind", tr.Kind().String()),
+		attribute.String("transport.endpoint", tr.Endpoint()),
+	}
+}
+
+// setStatus records the code and reason of the error, the status is left unset on success
+// and is an error on failure, except for the 4xx errors of the server which are the caller's.
+func setStatus(span trace.Span, err error, server bool) {
+	if err == nil {
+		return
+	}
+	e := errors.FromError(err)
+	span.RecordError(err)
+	span.SetAttributes(
+		attribute.Int("error.code", int(e.Code)),
+		attribute.String("error.reason", e.Reason),
+	)
+	if server && e.Code < 500 {
+		return
+	}
+	span.SetStatus(codes.Error, e.Message)
+}
+
+// TraceID returns a logger.Valuer of the trace id of the span in the context.
+func TraceID() logger.Valuer {
+	return func(ctx context.Context) interface{} {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			return sc.TraceID().String()
+		}
+		return ""
+	}
+}
+
+// SpanID returns a logger.Valuer of the span id of the span in the context.
+func SpanID() logger.Valuer {
+	return func(ctx context.Context) interface{} {
+		if sc := trace.SpanContextFromContext(ctx); sc.HasSpanID() {
+			return sc.SpanID().String()
+		}
+		return ""
+	}
+}
@@ -0,0 +1,88 @@
+package tracing
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware/internal/testutil"
+	"github.com/gotechbook/pkg/transport"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+
+	client := testutil.NewTransport(transport.KindGRPC, "127.0.0.1:9000", "/user.v1.User/Get")
+	_, _ = Client(WithTracerProvider(provider))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		if TraceID()(ctx) == "" || SpanID()(ctx) == "" {
+			t.Error("expected the trace and span ids in the context")
+		}
+		return nil, nil
+	})(transport.NewClientContext(context.Background(), client), nil)
+	if client.Request["traceparent"] == "" {
+		t.Fatal("expected the traceparent to be injected")
+	}
+
+	server := testutil.NewTransport(transport.KindGRPC, "127.0.0.1:9000", "/user.v1.User/Get")
+	server.Request = client.Request
+	_, err := Server(WithTracerProvider(provider))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.NotFound("USER_NOT_FOUND", "user not found")
+	})(transport.NewServerContext(context.Background(), server), nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, but got: %d", len(spans))
+	}
+	c, s := spans[0], spans[1]
+	if c.SpanKind != trace.SpanKindClient || s.SpanKind != trace.SpanKindServer || s.Name != "/user.v1.User/Get" {
+		t.Errorf("unexpected spans: %v %v", c.Name, s.Name)
+	}
+	if s.Parent.SpanID() != c.SpanContext.SpanID() || s.SpanContext.TraceID() != c.SpanContext.TraceID() {
+		t.Error("expected the server span to be the child of the client span")
+	}
+	if s.Status.Code != codes.Unset {
+		t.Errorf("expected the status of a 4xx server span to be unset, but got: %v", s.Status)
+	}
+	if c.Status.Code != codes.Unset {
+		t.Errorf("expected the status of a successful span to be unset, but got: %v", c.Status)
+	}
+	for _, kv := range s.Attributes {
+		if kv.Key == "error.reason" && kv.Value.AsString() != "USER_NOT_FOUND" {
+			t.Errorf("unexpected reason: %v", kv.Value.AsString())
+		}
+	}
+}
+
+func TestServerError(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(context.Background())
+
+	server := testutil.NewTransport(transport.KindGRPC, "127.0.0.1:9000", "/user.v1.User/Get")
+	_, _ = Server(WithTracerProvider(provider))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.InternalServer("DB_DOWN", "database is down")
+	})(transport.NewServerContext(context.Background(), server), nil)
+	client := testutil.NewTransport(transport.KindGRPC, "127.0.0.1:9000", "/user.v1.User/Get")
+	_, _ = Client(WithTracerProvider(provider))(func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.NotFound("USER_NOT_FOUND", "user not found")
+	})(transport.NewClientContext(context.Background(), client), nil)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, but got: %d", len(spans))
+	}
+	for _, span := range spans {
+		if span.Status.Code != codes.Error {
+			t.Errorf("expected the status of the %v span to be an error, but got: %v", span.SpanKind, span.Status)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package validate
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/errors"
+	"github.com/gotechbook/pkg/middleware"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// Reason is the reason of the errors of the invalid requests.
+const Reason = "VALIDATOR"
+
+type validator interface {
+	Validate() error
+}
+
+// allValidator is implemented by the messages generated by protoc-gen-validate
+// with all the violations, and by the types validating all their fields at once.
+type allValidator interface {
+	ValidateAll() error
+}
+
+// multiError is the error of ValidateAll of protoc-gen-validate.
+type multiError interface {
+	AllErrors() []error
+}
+
+// fieldError is the error of a field of protoc-gen-validate.
+type fieldError interface {
+	Field() string
+	Reason() string
+	Cause() error
+}
+
+// Server is a server middleware validating the requests with their ValidateAll or Validate method,
+// the invalid requests fail with errors.BadRequest, which metadata maps the invalid fields to their
+// violations and which gRPC status has the errdetails.BadRequest details.
+func Server() middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			var err error
+			switch v := req.(type) {
+			case allValidator:
+				err = v.ValidateAll()
+			case validator:
+				err = v.Validate()
+			}
+			if err != nil {
+				return nil, newError(err)
+			}
+			return handler(ctx, req)
+		}
+	}
+}
+
+// newError returns the errors.BadRequest of the validation error.
+func newError(err error) *errors.Error {
+	violations := fieldViolations(err, "")
+	e := errors.BadRequest(Reason, err.Error()).WithCause(err)
+	if len(violations) == 0 {
+		return e
+	}
+	md := make(map[string]string, len(violations))
+	for _, v := range violations {
+		md[v.Field] = v.Description
+	}
+	return e.WithMetadata(md).WithDetails(&errdetails.BadRequest{FieldViolations: violations})
+}
+
+// fieldViolations returns the violations of the validation error, the fields of the embedded
+// messages are prefixed with the path of their message, e.g. "address.city".
+func fieldViolations(err error, prefix string) []*errdetails.BadRequest_FieldViolation {
+	if me, ok := err.(multiError); ok {
+		var violations []*errdetails.BadRequest_FieldViolation
+		for _, e := range me.AllErrors() {
+			violations = append(violations, fieldViolations(e, prefix)...)
+		}
+		return violations
+	}
+	fe, ok := err.(fieldError)
+	if !ok {
+		return nil
+	}
+	field := prefix + fe.Field()
+	if cause := fe.Cause(); cause != nil {
+		if violations := fieldViolations(cause, field+"."); len(violations) > 0 {
+			return violations
+		}
+	}
+	return []*errdetails.BadRequest_FieldViolation{{Field: field, Description: fe.Reason()}}
+}
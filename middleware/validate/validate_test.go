@@ -0,0 +1,89 @@
+package validate
+
+import (
+	"context"
+	"fmt"
+	"github.com/gotechbook/pkg/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+)
+
+// testFieldError mimics the errors generated by protoc-gen-validate.
+type testFieldError struct {
+	field  string
+	reason string
+	cause  error
+}
+
+func (e testFieldError) Field() string  { return e.field }
+func (e testFieldError) Reason() string { return e.reason }
+func (e testFieldError) Cause() error   { return e.cause }
+func (e testFieldError) Error() string  { return fmt.Sprintf("invalid %s: %s", e.field, e.reason) }
+
+type testMultiError []error
+
+func (m testMultiError) AllErrors() []error { return m }
+func (m testMultiError) Error() string {
+	msgs := make([]string, 0, len(m))
+	for _, err := range m {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type testRequest struct {
+	err error
+}
+
+func (r *testRequest) Validate() error    { return fmt.Errorf("unexpected Validate") }
+func (r *testRequest) ValidateAll() error { return r.err }
+
+type testSimpleRequest struct{}
+
+func (r *testSimpleRequest) Validate() error { return fmt.Errorf("invalid request") }
+
+func TestServer(t *testing.T) {
+	h := Server()(func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil })
+
+	if reply, err := h(context.Background(), &testRequest{}); err != nil || reply != "ok" {
+		t.Fatalf("expected a valid request, but got: %v %v", reply, err)
+	}
+	if reply, err := h(context.Background(), "no validation"); err != nil || reply != "ok" {
+		t.Fatalf("expected a request without validation, but got: %v %v", reply, err)
+	}
+
+	_, err := h(context.Background(), &testRequest{err: testMultiError{
+		testFieldError{field: "Name", reason: "value length must be at least 1 runes"},
+		testFieldError{field: "Address", reason: "embedded message failed validation", cause: testMultiError{
+			testFieldError{field: "City", reason: "value is required"},
+		}},
+	}})
+	if !errors.IsBadRequest(err) || errors.Reason(err) != Reason {
+		t.Fatalf("expected a bad request, but got: %v", err)
+	}
+	md := errors.FromError(err).Metadata
+	if len(md) != 2 || md["Name"] != "value length must be at least 1 runes" || md["Address.City"] != "value is required" {
+		t.Errorf("unexpected metadata: %v", md)
+	}
+
+	s, _ := status.FromError(err)
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, d := range s.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations = br.FieldViolations
+		}
+	}
+	if len(violations) != 2 || violations[1].Field != "Address.City" {
+		t.Errorf("unexpected field violations: %v", violations)
+	}
+	if details := errors.FromError(s.Err()).Details(); len(details) != 1 {
+		t.Errorf("expected the details to survive the status, but got: %v", details)
+	}
+
+	_, err = h(context.Background(), &testSimpleRequest{})
+	if !errors.IsBadRequest(err) || errors.FromError(err).Message != "invalid request" {
+		t.Errorf("expected a bad request, but got: %v", err)
+	}
+}
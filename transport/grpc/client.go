@@ -30,7 +30,8 @@ func DialInsecure(ctx context.Context, opts ...ClientOption) (*grpc.ClientConn,
 
 func dial(ctx context.Context, insecure bool, opts ...ClientOption) (*grpc.ClientConn, error) {
 	client := &Client{
-		timeout: 2000 * time.Millisecond,
+		timeout:    2000 * time.Millisecond,
+		middleware: middleware.NewMatcher(),
 	}
 	for _, o := range opts {
 		o(client)
@@ -2,9 +2,11 @@ package grpc
 
 import (
 	"context"
+	"github.com/gotechbook/pkg/middleware"
 	"github.com/gotechbook/pkg/transport"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
 )
 
 func (c *Client) streamClientInterceptor() grpc.StreamClientInterceptor {
@@ -43,10 +45,42 @@ func (c *Client) unaryClientInterceptor() grpc.UnaryClientInterceptor {
 				}
 				ctx = metadata.AppendToOutgoingContext(ctx, ks...)
 			}
-			return reply, invoker(ctx, method, req, reply, cc, opts...)
+			// every call of the handler, e.g. a hedged attempt, decodes its own reply,
+			// so that the concurrent attempts do not share it
+			r := newReply(reply)
+			return r, invoker(ctx, method, req, r, cc, opts...)
+		}
+		if next := c.middleware.Matcher(method); len(next) > 0 {
+			h = middleware.Chain(next...)(h)
 		}
 
-		_, err := h(ctx, req)
+		r, err := h(ctx, req)
+		if err == nil {
+			copyReply(reply, r)
+		}
 		return err
 	}
 }
+
+// newReply returns an empty message of the type of the reply, or the reply itself
+// if it is not a protobuf message.
+func newReply(reply interface{}) interface{} {
+	if m, ok := reply.(proto.Message); ok {
+		return m.ProtoReflect().New().Interface()
+	}
+	return reply
+}
+
+// copyReply copies the reply returned by the middleware to the reply of the call.
+func copyReply(dst, src interface{}) {
+	d, ok := dst.(proto.Message)
+	if !ok {
+		return
+	}
+	s, ok := src.(proto.Message)
+	if !ok || d == s || d.ProtoReflect().Descriptor() != s.ProtoReflect().Descriptor() {
+		return
+	}
+	proto.Reset(d)
+	proto.Merge(d, s)
+}
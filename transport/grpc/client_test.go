@@ -0,0 +1,85 @@
+package grpc
+
+import (
+	"context"
+	"github.com/gotechbook/pkg/middleware"
+	"github.com/gotechbook/pkg/middleware/retry"
+	"github.com/gotechbook/pkg/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	cc, err := grpc.Dial("127.0.0.1:0", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	var operation string
+	c := &Client{middleware: middleware.NewMatcher()}
+	WithClientMiddleware(func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			if tr, ok := transport.FromClientContext(ctx); ok {
+				operation = tr.Operation()
+				tr.RequestHeader().Set("x-md-test", "1")
+			}
+			return handler(ctx, req)
+		}
+	})(c)
+
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if md, _ := metadata.FromOutgoingContext(ctx); len(md.Get("x-md-test")) != 1 {
+			t.Errorf("expected the header set by the middleware, but got: %v", md)
+		}
+		return nil
+	}
+	if err = c.unaryClientInterceptor()(context.Background(), "/test.Service/Method", nil, nil, cc, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if operation != "/test.Service/Method" {
+		t.Errorf("expected the middleware to run for the operation, but got: %q", operation)
+	}
+}
+
+func TestUnaryClientInterceptorHedging(t *testing.T) {
+	cc, err := grpc.Dial("127.0.0.1:0", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cc.Close()
+
+	c := &Client{middleware: middleware.NewMatcher()}
+	WithClientMiddleware(retry.Client(retry.WithHedging(5 * time.Millisecond)))(c)
+
+	var calls int32
+	done := make(chan struct{})
+	// the invoker decodes into the reply it is given, like the grpc invoker
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			defer close(done)
+			<-ctx.Done()
+			// the slow attempt still writes its reply after the hedged one won
+			reply.(*wrapperspb.StringValue).Value = "slow"
+			return ctx.Err()
+		}
+		reply.(*wrapperspb.StringValue).Value = "hedged"
+		return nil
+	}
+	reply := &wrapperspb.StringValue{}
+	if err = c.unaryClientInterceptor()(context.Background(), "/test.Service/Method", nil, reply, cc, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Value != "hedged" {
+		t.Errorf("expected the hedged reply, but got: %q", reply.Value)
+	}
+	<-done
+	if reply.Value != "hedged" {
+		t.Errorf("expected the reply not to be written by the slow attempt, but got: %q", reply.Value)
+	}
+}
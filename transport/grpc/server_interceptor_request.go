@@ -10,6 +10,8 @@ import (
 
 const clientRequestIDKey = "X-Request-ID"
 
+// RequestLogUnaryInterceptor logs the requests and responses.
+// Deprecated: use the logging.Server middleware instead.
 func RequestLogUnaryInterceptor() grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 		starTime := time.Now()
@@ -25,12 +27,14 @@ func RequestLogUnaryInterceptor() grpc.UnaryServerInterceptor {
 	}
 }
 
+// RequestLogStreamInterceptor logs the messages of the streams.
+// Deprecated: use the logging.Server middleware instead.
 func RequestLogStreamInterceptor() grpc.StreamServerInterceptor {
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		lss := &streamLog{
 			ServerStream: ss,
 			fullMethod:   info.FullMethod,
-			cid:          ExtractIncoming(ss.Context()).Get(clientRequestIDKey)[0],
+			cid:          headerCarrier(ExtractIncoming(ss.Context())).Get(clientRequestIDKey),
 			rid:          uuid.New().String(),
 		}
 		return handler(srv, lss)
@@ -51,7 +55,7 @@ func (r *streamLog) SendMsg(m any) error {
 	logger.Infow("method", r.fullMethod,
 		"id", r.rid,
 		"cid", r.cid,
-		"request", m,
+		"response", m,
 	)
 	return nil
 }
@@ -63,7 +67,7 @@ func (r *streamLog) RecvMsg(m any) error {
 	logger.Infow("method", r.fullMethod,
 		"id", r.rid,
 		"cid", r.cid,
-		"response", m,
+		"request", m,
 	)
 	return nil
 }